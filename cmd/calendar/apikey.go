@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"calendar/internal/config"
+	"calendar/internal/databases"
+	"calendar/internal/repos"
+	"calendar/internal/services"
+)
+
+const apiKeyUsage = `usage:
+  calendar apikey create -owner <user> -name <name> -scopes events:read,events:write[,admin]
+  calendar apikey list [-owner <user>]
+  calendar apikey revoke <id>`
+
+// runAPIKey выполняет команду управления ключами API напрямую через БД, без проверки прав:
+// так выпускается первый ключ с правом admin.
+func runAPIKey(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", apiKeyUsage)
+	}
+
+	db, err := databases.NewPostgres(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	repo := repos.NewPGEventStorage(db.DB)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		owner := fs.String("owner", "", "пользователь, от имени которого действует ключ")
+		name := fs.String("name", "", "название ключа")
+		scopes := fs.String("scopes", "", "права через запятую")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		k := &repos.APIKey{OwnerID: *owner, Name: *name}
+		if *scopes != "" {
+			k.Scopes = strings.Split(*scopes, ",")
+		}
+		key, err := services.NewEventsService(repo).MintAPIKey(ctx, k)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("id:  %s\nkey: %s\n", k.ID, key)
+		return nil
+
+	case "list":
+		fs := flag.NewFlagSet("apikey list", flag.ContinueOnError)
+		owner := fs.String("owner", "", "только ключи этого пользователя")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		keys, err := repo.ListAPIKeys(ctx, *owner)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tOWNER\tNAME\tPREFIX\tSCOPES\tCREATED\tREVOKED")
+		for _, k := range keys {
+			revoked := "-"
+			if k.RevokedAt != nil {
+				revoked = k.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.OwnerID, k.Name, k.Prefix,
+				strings.Join(k.Scopes, ","), k.CreatedAt.Format(time.RFC3339), revoked)
+		}
+		return tw.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("missing key id\n%s", apiKeyUsage)
+		}
+		if err := repo.RevokeAPIKey(ctx, args[1]); errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("api key %s not found or already revoked", args[1])
+		} else if err != nil {
+			return err
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown subcommand %q\n%s", args[0], apiKeyUsage)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 	// "time"
 
 	"calendar/internal/application"
@@ -16,6 +17,14 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
+	// Управление ключами API: calendar apikey ...
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := runAPIKey(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("apikey: %v", err)
+		}
+		return
+	}
+
 	// 2. Авто‑миграции (только если включены в конфиге)
 	if cfg.Postgres.AutoMigrate {
 		if err := migrations.Up(cfg.Postgres.DSN); err != nil {
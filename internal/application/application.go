@@ -9,13 +9,22 @@ import (
 	"syscall"
 	"time"
 
+	"calendar/internal/auth"
+	"calendar/internal/caldav"
 	"calendar/internal/config"
 	"calendar/internal/databases"
 	"calendar/internal/handlers"
+	"calendar/internal/health"
 	"calendar/internal/kafka"
 	"calendar/internal/logger"
+	"calendar/internal/metrics"
+	"calendar/internal/migrations"
+	"calendar/internal/ratelimit"
+	"calendar/internal/reminders"
 	"calendar/internal/repos"
+	"calendar/internal/requestlog"
 	"calendar/internal/services"
+	"calendar/internal/tracing"
 )
 
 type App struct {
@@ -23,10 +32,12 @@ type App struct {
 	log    logger.Logger
 	db     *databases.Postgres
 	server *http.Server
+	tracer *tracing.Provider
 
 	events   services.EventsService
 	producer *kafka.Producer
 	consumer *kafka.Consumer
+	reminder *reminders.Scheduler
 }
 
 // NewApp собирает все зависимости: логгер, БД, storage, HTTP‑хендлеры и сервер.
@@ -34,14 +45,33 @@ func NewApp(cfg *config.Config) (*App, error) {
 	// 1. Логгер
 	log := logger.InitLogger(cfg.Logging.Level)
 
+	// трассировка — до БД и Kafka, чтобы их спаны сразу уходили в экспортёр
+	tracer, err := tracing.NewProvider(cfg)
+	if err != nil {
+		log.Error("failed to configure tracing", "error", err)
+		return nil, err
+	}
+
 	// 2. База данных
 	db, err := databases.NewPostgres(cfg)
 	if err != nil {
 		log.Error("failed to connect to postgres", "error", err)
+		_ = tracer.Shutdown(context.Background())
 		return nil, err
 	}
 	log.Info("connected to postgres")
 
+	metrics.RegisterDB(db.DB, "calendar")
+
+	// версия схемы, до которой должна быть доведена база, — для пробы готовности
+	schemaVersion, err := migrations.Latest()
+	if err != nil {
+		log.Error("failed to read migrations", "error", err)
+		_ = db.Close()
+		_ = tracer.Shutdown(context.Background())
+		return nil, err
+	}
+
 	// 3. Репозиторий событий
 	eventsRepo := repos.NewPGEventStorage(db.DB)
 
@@ -49,45 +79,81 @@ func NewApp(cfg *config.Config) (*App, error) {
 	eventsService := services.NewEventsService(eventsRepo)
 
 	// 5. HTTP‑хендлеры
-	h := handlers.NewHandlers(log, eventsService)
+	h := handlers.NewHandlers(eventsService)
+
+	authenticator, err := auth.NewAuthenticator(cfg, log, eventsRepo)
+	if err != nil {
+		log.Error("failed to configure authentication", "error", err)
+		_ = db.Close()
+		_ = tracer.Shutdown(context.Background())
+		return nil, err
+	}
+
+	limiter, err := ratelimit.NewLimiter(cfg, log)
+	if err != nil {
+		log.Error("failed to configure rate limiting", "error", err)
+		_ = db.Close()
+		_ = tracer.Shutdown(context.Background())
+		return nil, err
+	}
 
 	// 6. HTTP‑роутер
 	mux := http.NewServeMux()
 
-	// healthcheck
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
+	// 7. Kafka producer (пересылает outbox, который пишет репозиторий событий)
+	producer := kafka.NewProducer(cfg, log, eventsRepo)
+
+	// 8. Kafka consumer
+	consumer := kafka.NewConsumer(cfg, log)
+
+	// пробы /livez и /readyz: готовность — база доступна, схема актуальна, producer и consumer работают
+	checker := health.NewChecker(cfg, log)
+	checker.Add("postgres", db.DB.PingContext)
+	checker.Add("migrations", func(ctx context.Context) error {
+		return migrations.Check(ctx, db.DB, schemaVersion)
 	})
+	checker.Add("kafka_producer", func(context.Context) error { return producer.Health() })
+	checker.Add("kafka_consumer", func(context.Context) error { return consumer.Health() })
+	checker.RegisterRoutes(mux)
+
+	// метрики Prometheus
+	mux.Handle("/metrics", metrics.Handler())
 
 	// маршруты /api/events..., внутри RegisterRoutes — CRUD
 	h.RegisterRoutes(mux)
 
-	// 7. HTTP‑сервер
+	// CalDAV для синхронизации с клиентами календарей: /caldav/{owner_id}/...
+	caldav.NewHandler(log, eventsService).RegisterRoutes(mux)
+
+	// 9. HTTP‑сервер
 	addr := fmt.Sprintf("%s:%d", cfg.HTTPServer.Host, cfg.HTTPServer.Port)
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+		Addr: addr,
+		// лимит по IP проверяется до аутентификации, чтобы ограничить и перебор ключей и токенов;
+		// пользователь запроса — из JWT или ключа API в Authorization, права проверяет сервис событий;
+		// лимит клиента считается по уже известному ключу или пользователю; спан запроса охватывает
+		// всю цепочку, а запись о запросе в логе — и отклонённые лимитом и аутентификацией запросы
+		Handler: tracing.Middleware(mux, metrics.Middleware(mux,
+			requestlog.Middleware(log, limiter.IPMiddleware(authenticator.Middleware(limiter.Middleware(mux)))))),
 	}
 
-	// 8. Kafka producer
-	producer := kafka.NewProducer(cfg, log, eventsRepo)
-
-	// 9. Kafka consumer
-	consumer := kafka.NewConsumer(cfg, log)
+	// 10. Планировщик напоминаний
+	reminder := reminders.NewScheduler(cfg, log, eventsRepo)
 
 	return &App{
 		cfg:      cfg,
 		log:      log,
 		db:       db,
 		server:   srv,
+		tracer:   tracer,
 		events:   eventsService,
 		producer: producer,
 		consumer: consumer,
+		reminder: reminder,
 	}, nil
 }
 
-// Run запускает HTTP‑сервер, Kafka producer и consumer, и делает graceful shutdown.
+// Run запускает HTTP‑сервер, Kafka producer и consumer, планировщик напоминаний и делает graceful shutdown.
 func (a *App) Run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -115,6 +181,13 @@ func (a *App) Run() error {
 	}
 	a.log.Info("kafka consumer started")
 
+	// запускаем планировщик напоминаний
+	if err := a.reminder.Start(ctx); err != nil {
+		a.log.Error("failed to start reminder scheduler", "error", err)
+		return err
+	}
+	a.log.Info("reminder scheduler started")
+
 	// ждём сигнала ОС
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
@@ -122,13 +195,18 @@ func (a *App) Run() error {
 	<-stop
 	a.log.Info("shutdown signal received")
 
-	// отменяем контекст для остановки producer и consumer
+	// отменяем контекст для остановки producer, consumer и планировщика
 	cancel()
 
 	// контекст для graceful shutdown
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
+	// останавливаем планировщик напоминаний
+	if err := a.reminder.Stop(); err != nil {
+		a.log.Error("reminder scheduler stop error", "error", err)
+	}
+
 	// останавливаем Kafka consumer
 	if err := a.consumer.Stop(); err != nil {
 		a.log.Error("kafka consumer stop error", "error", err)
@@ -144,6 +222,11 @@ func (a *App) Run() error {
 		a.log.Error("http server shutdown error", "error", err)
 	}
 
+	// отправляем оставшиеся спаны
+	if err := a.tracer.Shutdown(shutdownCtx); err != nil {
+		a.log.Error("tracing shutdown error", "error", err)
+	}
+
 	// закрываем БД
 	if err := a.db.Close(); err != nil {
 		a.log.Error("postgres close error", "error", err)
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+)
+
+// Права ключей API.
+const (
+	// ScopeEventsRead — чтение событий, календарей и занятости.
+	ScopeEventsRead = "events:read"
+	// ScopeEventsWrite — всё, что доступно пользователю-владельцу ключа, кроме управления ключами.
+	ScopeEventsWrite = "events:write"
+	// ScopeAdmin — выпуск и отзыв ключей API для любых пользователей.
+	ScopeAdmin = "admin"
+	// ScopeFeedRead — только подписка на календарь владельца .ics с ключом в URL (?token=...);
+	// в заголовке Authorization такой ключ ничего не даёт.
+	ScopeFeedRead = "feed:read"
+)
+
+// Scopes — все права, которые можно выдать ключу API.
+var Scopes = []string{ScopeEventsRead, ScopeEventsWrite, ScopeAdmin, ScopeFeedRead}
+
+const (
+	apiKeyPrefix = "cal_"
+	// apiKeyShownPrefix — сколько первых символов ключа хранится, чтобы узнавать его в списке.
+	apiKeyShownPrefix = len(apiKeyPrefix) + 8
+)
+
+type (
+	scopesKey   struct{}
+	apiKeyIDKey struct{}
+)
+
+// WithScopes возвращает контекст запроса, ограниченного правами scopes (запрос с ключом API).
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// ScopesFrom возвращает права запроса; ok=false — запрос правами не ограничен
+// (пользователь с токеном или анонимный).
+func ScopesFrom(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(scopesKey{}).([]string)
+	return scopes, ok
+}
+
+// WithAPIKeyID возвращает контекст запроса, выполняемого с ключом API id.
+func WithAPIKeyID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, apiKeyIDKey{}, id)
+}
+
+// APIKeyIDFrom возвращает ID ключа API запроса; ok=false — запрос без ключа.
+func APIKeyIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(apiKeyIDKey{}).(string)
+	return id, ok
+}
+
+// HasScope сообщает, разрешено ли запросу действие с правом scope.
+// admin включает все права, events:write — events:read.
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, ok := ScopesFrom(ctx)
+	if !ok {
+		return scope != ScopeAdmin
+	}
+	switch {
+	case slices.Contains(scopes, ScopeAdmin), slices.Contains(scopes, scope):
+		return true
+	case scope == ScopeEventsRead:
+		return slices.Contains(scopes, ScopeEventsWrite)
+	default:
+		return false
+	}
+}
+
+// NewAPIKey генерирует новый ключ API и возвращает его вместе с началом для показа и хэшем для хранения.
+func NewAPIKey() (key, prefix, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:apiKeyShownPrefix], HashAPIKey(key), nil
+}
+
+// HashAPIKey возвращает хэш ключа, под которым он хранится. Ключи случайные и длинные,
+// поэтому соль и медленный хэш не нужны.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
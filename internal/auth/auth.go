@@ -0,0 +1,17 @@
+// Package auth определяет, от имени какого пользователя выполняется запрос.
+package auth
+
+import "context"
+
+type userKey struct{}
+
+// WithUser возвращает контекст запроса от имени пользователя userID.
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// UserFrom возвращает пользователя запроса; ok=false, если запрос анонимный.
+func UserFrom(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userKey{}).(string)
+	return userID, ok && userID != ""
+}
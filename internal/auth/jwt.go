@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"calendar/internal/config"
+)
+
+// ErrInvalidToken — токен не прошёл проверку подписи или сроков.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Verifier проверяет JWT, подписанные HS256 общим секретом или RS256 ключом из JWKS.
+type Verifier struct {
+	secret   []byte
+	keys     map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// minHMACSecret — минимальная длина секрета HS256: не меньше размера хеша SHA-256 (RFC 7518, 3.2).
+const minHMACSecret = 32
+
+// knownSecrets — секреты, которые когда-то лежали в репозитории; токен с ними может подписать кто угодно.
+var knownSecrets = []string{"local-dev-secret"}
+
+// NewVerifier создаёт проверку токенов; нужен хотя бы один из hmac_secret и jwks_file.
+// Короткий или известный hmac_secret отвергается: с ним любой подписал бы токен от имени любого пользователя.
+func NewVerifier(cfg config.AuthConfig) (*Verifier, error) {
+	if cfg.HMACSecret != "" {
+		if slices.Contains(knownSecrets, cfg.HMACSecret) {
+			return nil, errors.New("auth: hmac_secret is a published default, set your own in AUTH_HMAC_SECRET")
+		}
+		if len(cfg.HMACSecret) < minHMACSecret {
+			return nil, fmt.Errorf("auth: hmac_secret must be at least %d bytes", minHMACSecret)
+		}
+	}
+
+	v := &Verifier{
+		secret:   []byte(cfg.HMACSecret),
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		leeway:   cfg.Leeway,
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("load jwks %s: %w", cfg.JWKSFile, err)
+		}
+		v.keys = keys
+	}
+
+	if len(v.secret) == 0 && len(v.keys) == 0 {
+		return nil, errors.New("auth: hmac_secret or jwks_file is required")
+	}
+	return v, nil
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type tokenClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+}
+
+// audience — claim aud: по RFC 7519 это строка или массив строк.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Verify проверяет подпись, сроки действия, iss и aud токена и возвращает его subject.
+func (v *Verifier) Verify(token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var h tokenHeader
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return "", fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	if err := v.verifySignature(h, parts[0]+"."+parts[1], sig); err != nil {
+		return "", err
+	}
+
+	var c tokenClaims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return "", fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := v.verifyClaims(c, now); err != nil {
+		return "", err
+	}
+	return c.Subject, nil
+}
+
+func (v *Verifier) verifySignature(h tokenHeader, signed string, sig []byte) error {
+	switch h.Alg {
+	case "HS256":
+		if len(v.secret) == 0 {
+			return fmt.Errorf("%w: HS256 tokens are not accepted", ErrInvalidToken)
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+
+	case "RS256":
+		key, ok := v.key(h.Kid)
+		if !ok {
+			return fmt.Errorf("%w: unknown key %q", ErrInvalidToken, h.Kid)
+		}
+		digest := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, h.Alg)
+	}
+}
+
+// key возвращает ключ RS256 по kid; токен без kid подходит, если ключ в JWKS один.
+func (v *Verifier) key(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+func (v *Verifier) verifyClaims(c tokenClaims, now time.Time) error {
+	if c.Subject == "" {
+		return fmt.Errorf("%w: sub is required", ErrInvalidToken)
+	}
+	if c.ExpiresAt == nil {
+		return fmt.Errorf("%w: exp is required", ErrInvalidToken)
+	}
+	if !now.Before(numericDate(*c.ExpiresAt).Add(v.leeway)) {
+		return fmt.Errorf("%w: token is expired", ErrInvalidToken)
+	}
+	if c.NotBefore != nil && now.Add(v.leeway).Before(numericDate(*c.NotBefore)) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if v.audience != "" && !slices.Contains(c.Audience, v.audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// numericDate переводит NumericDate (секунды Unix, возможно дробные) во время.
+func numericDate(sec float64) time.Time {
+	return time.Unix(0, int64(sec*float64(time.Second)))
+}
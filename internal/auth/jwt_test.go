@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"calendar/internal/config"
+)
+
+const testSecret = "test-secret-0123456789abcdef01234"
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signHS256 собирает токен с заголовком header, подписанный HMAC-SHA256 секретом secret.
+func signHS256(t *testing.T, header, claims map[string]any, secret string) string {
+	t.Helper()
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signRS256 собирает токен с заголовком header, подписанный RSA-ключом key.
+func signRS256(t *testing.T, header, claims map[string]any, key *rsa.PrivateKey) string {
+	t.Helper()
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// writeJWKS записывает открытые ключи keys (по kid) в JWKS-файл и возвращает путь к нему.
+func writeJWKS(t *testing.T, keys map[string]*rsa.PrivateKey) string {
+	t.Helper()
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	key, otherKey := generateKey(t), generateKey(t)
+
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub": "user-1",
+			"iss": "https://auth.example.com",
+			"aud": "calendar",
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	hs256 := map[string]any{"alg": "HS256", "typ": "JWT"}
+	rs256 := map[string]any{"alg": "RS256", "typ": "JWT", "kid": "k1"}
+
+	cfg := config.AuthConfig{
+		HMACSecret: testSecret,
+		JWKSFile:   writeJWKS(t, map[string]*rsa.PrivateKey{"k1": key}),
+		Issuer:     "https://auth.example.com",
+		Audience:   "calendar",
+		Leeway:     30 * time.Second,
+	}
+	v, err := NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		// want — ожидаемый subject; пустой — токен должен быть отклонён.
+		want string
+	}{
+		{"HS256", signHS256(t, hs256, claims(nil), testSecret), "user-1"},
+		{"HS256 with a wrong secret", signHS256(t, hs256, claims(nil), "guess"), ""},
+		{"RS256", signRS256(t, rs256, claims(nil), key), "user-1"},
+		{"RS256 without kid and a single key", signRS256(t, map[string]any{"alg": "RS256"}, claims(nil), key), "user-1"},
+		{"RS256 with an unknown kid", signRS256(t, map[string]any{"alg": "RS256", "kid": "k2"}, claims(nil), key), ""},
+		{"RS256 signed by another key", signRS256(t, rs256, claims(nil), otherKey), ""},
+		{"RS256 header with an HMAC signature", signHS256(t, rs256, claims(nil), testSecret), ""},
+		{"alg none", encodeSegment(t, map[string]any{"alg": "none"}) + "." + encodeSegment(t, claims(nil)) + ".", ""},
+		{"alg HS512", signHS256(t, map[string]any{"alg": "HS512"}, claims(nil), testSecret), ""},
+		{"missing alg", signHS256(t, map[string]any{"typ": "JWT"}, claims(nil), testSecret), ""},
+		{"aud array", signHS256(t, hs256, claims(map[string]any{"aud": []string{"other", "calendar"}}), testSecret), "user-1"},
+		{"wrong audience", signHS256(t, hs256, claims(map[string]any{"aud": "other"}), testSecret), ""},
+		{"wrong issuer", signHS256(t, hs256, claims(map[string]any{"iss": "https://evil.example.com"}), testSecret), ""},
+		{"expired", signHS256(t, hs256, claims(map[string]any{"exp": now.Add(-time.Minute).Unix()}), testSecret), ""},
+		{"expired within leeway", signHS256(t, hs256, claims(map[string]any{"exp": now.Add(-10 * time.Second).Unix()}), testSecret), "user-1"},
+		{"not valid yet", signHS256(t, hs256, claims(map[string]any{"nbf": now.Add(time.Minute).Unix()}), testSecret), ""},
+		{"nbf within leeway", signHS256(t, hs256, claims(map[string]any{"nbf": now.Add(10 * time.Second).Unix()}), testSecret), "user-1"},
+		{"missing exp", signHS256(t, hs256, claims(map[string]any{"exp": nil}), testSecret), ""},
+		{"missing sub", signHS256(t, hs256, claims(map[string]any{"sub": nil}), testSecret), ""},
+		{"two segments", encodeSegment(t, hs256) + "." + encodeSegment(t, claims(nil)), ""},
+		{"garbage", "not.a.token", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token, now)
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("Verify = %q, %v; want ErrInvalidToken", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Verify = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyDisabledAlgorithms(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	key := generateKey(t)
+	claims := map[string]any{"sub": "user-1", "exp": now.Add(time.Hour).Unix()}
+
+	tests := []struct {
+		name  string
+		cfg   config.AuthConfig
+		token string
+	}{
+		{
+			name:  "HS256 without hmac_secret",
+			cfg:   config.AuthConfig{JWKSFile: writeJWKS(t, map[string]*rsa.PrivateKey{"k1": key})},
+			token: signHS256(t, map[string]any{"alg": "HS256"}, claims, ""),
+		},
+		{
+			name:  "RS256 without jwks_file",
+			cfg:   config.AuthConfig{HMACSecret: testSecret},
+			token: signRS256(t, map[string]any{"alg": "RS256", "kid": "k1"}, claims, key),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewVerifier(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewVerifier: %v", err)
+			}
+			if sub, err := v.Verify(tt.token, now); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify = %q, %v; want ErrInvalidToken", sub, err)
+			}
+		})
+	}
+}
+
+func TestNewVerifierRejectsWeakSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{"published default", "local-dev-secret", true},
+		{"too short", strings.Repeat("s", minHMACSecret-1), true},
+		{"long enough", strings.Repeat("s", minHMACSecret), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(config.AuthConfig{HMACSecret: tt.secret})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewVerifier(%q) error = %v, want error %v", tt.secret, err, tt.wantErr)
+			}
+		})
+	}
+}
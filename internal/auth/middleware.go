@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"calendar/internal/config"
+	"calendar/internal/logger"
+	"calendar/internal/repos"
+)
+
+// BasicChallenge — WWW-Authenticate для клиентов, которые умеют только HTTP Basic (CalDAV).
+const BasicChallenge = `Basic realm="calendar", charset="UTF-8"`
+
+// APIKeysRepo — хранилище ключей API, по которому проверяется заголовок "Authorization: ApiKey ...".
+type APIKeysRepo interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (*repos.APIKey, error)
+}
+
+// Authenticator определяет пользователя запроса по токену или ключу API из заголовка Authorization.
+type Authenticator struct {
+	log      logger.Logger
+	verifier *Verifier
+	keys     APIKeysRepo
+}
+
+// NewAuthenticator создаёт Authenticator по настройкам cfg.Auth.
+func NewAuthenticator(cfg *config.Config, log logger.Logger, keys APIKeysRepo) (*Authenticator, error) {
+	verifier, err := NewVerifier(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{
+		log:      log,
+		verifier: verifier,
+		keys:     keys,
+	}, nil
+}
+
+// Middleware кладёт в контекст запроса пользователя из "Authorization: Bearer <jwt>" (subject токена),
+// "Authorization: ApiKey <key>" (владелец ключа, запрос ограничен правами ключа) или
+// "Authorization: Basic" с владельцем ключа в качестве имени и ключом в качестве пароля — так
+// подключаются CalDAV-клиенты, которые не умеют других схем.
+// Запросы без заголовка проходят дальше анонимными: доступ проверяет сервис (подписка .ics
+// проверяет собственный токен в URL).
+// Запросы с неверным или просроченным токеном либо неизвестным ключом отклоняются с 401.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, credentials, _ := strings.Cut(header, " ")
+		credentials = strings.TrimSpace(credentials)
+
+		switch {
+		case strings.EqualFold(scheme, "Bearer"):
+			userID, err := a.verifier.Verify(credentials, time.Now())
+			if err != nil {
+				logger.FromContext(r.Context()).Debug("token rejected", "err", err)
+				unauthorized(w, `Bearer error="invalid_token"`, err.Error())
+				return
+			}
+			logger.Annotate(r.Context(), "user_id", userID)
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), userID)))
+
+		case strings.EqualFold(scheme, "ApiKey"):
+			a.serveAPIKey(w, r, next, "ApiKey", "", credentials)
+
+		case strings.EqualFold(scheme, "Basic"):
+			decoded, err := base64.StdEncoding.DecodeString(credentials)
+			user, key, ok := strings.Cut(string(decoded), ":")
+			if err != nil || !ok || user == "" {
+				unauthorized(w, BasicChallenge, "malformed basic credentials")
+				return
+			}
+			a.serveAPIKey(w, r, next, BasicChallenge, user, key)
+
+		default:
+			unauthorized(w, "Bearer", "unsupported authorization scheme")
+		}
+	})
+}
+
+// serveAPIKey выполняет запрос от имени владельца ключа API. Если задан ownerID
+// (имя пользователя в HTTP Basic), ключ должен принадлежать ему.
+func (a *Authenticator) serveAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, challenge, ownerID, credentials string) {
+	key, err := a.keys.GetAPIKeyByHash(r.Context(), HashAPIKey(credentials))
+	if errors.Is(err, sql.ErrNoRows) || err == nil && ownerID != "" && key.OwnerID != ownerID {
+		unauthorized(w, challenge, "invalid api key")
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("api key lookup failed", "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	logger.Annotate(r.Context(), "user_id", key.OwnerID, "api_key_id", key.ID)
+	ctx := WithScopes(WithUser(r.Context(), key.OwnerID), key.Scopes)
+	ctx = WithAPIKeyID(ctx, key.ID)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+func unauthorized(w http.ResponseWriter, challenge, msg string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	writeError(w, http.StatusUnauthorized, msg)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,279 @@
+// Package caldav реализует CalDAV-сервер (RFC 4791) поверх сервиса событий:
+// у каждого пользователя одна коллекция-календарь, каждое событие (серия вместе
+// с переопределениями вхождений) — отдельный ресурс .ics.
+//
+// Пути:
+//
+//	/caldav/{owner_id}/                — принципал и calendar-home-set
+//	/caldav/{owner_id}/calendar/       — календарь
+//	/caldav/{owner_id}/calendar/{uid}.ics — событие
+package caldav
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar/internal/auth"
+	"calendar/internal/ics"
+	"calendar/internal/logger"
+	"calendar/internal/repos"
+	"calendar/internal/services"
+)
+
+// Prefix — путь, под которым смонтирован CalDAV.
+const Prefix = "/caldav/"
+
+// calendarName — имя единственной коллекции пользователя.
+const calendarName = "calendar"
+
+// maxResourceSize — предельный размер ресурса .ics в PUT.
+const maxResourceSize = 1 << 20
+
+// Handler обслуживает CalDAV-запросы.
+type Handler struct {
+	log    logger.Logger
+	events services.EventsService
+}
+
+// NewHandler создаёт новый CalDAV-хендлер.
+func NewHandler(log logger.Logger, events services.EventsService) *Handler {
+	return &Handler{
+		log:    log,
+		events: events,
+	}
+}
+
+// RegisterRoutes монтирует CalDAV в mux рядом с REST API.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle(Prefix, h)
+}
+
+// ServeHTTP реализует http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p, ok := parsePath(r.URL.EscapedPath())
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Клиенты сначала приходят без пароля; 401 с вызовом Basic нужен на любой запрос,
+	// даже если ответ (например, PROPFIND принципала) не требует данных сервиса.
+	if _, ok := auth.UserFrom(r.Context()); !ok && r.Method != http.MethodOptions {
+		h.serviceError(w, r, "", services.ErrUnauthenticated)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodOptions:
+		h.options(w, p)
+	case "PROPFIND":
+		h.propfind(w, r, p)
+	case "REPORT":
+		h.report(w, r, p)
+	case http.MethodGet, http.MethodHead:
+		h.get(w, r, p)
+	case http.MethodPut:
+		h.put(w, r, p)
+	case http.MethodDelete:
+		h.delete(w, r, p)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Пути ресурсов
+
+type resourceKind int
+
+const (
+	kindPrincipal resourceKind = iota
+	kindCalendar
+	kindObject
+)
+
+type resourcePath struct {
+	kind    resourceKind
+	ownerID string
+	uid     string // только у kindObject
+}
+
+// parsePath разбирает экранированный путь запроса (или href из REPORT).
+func parsePath(escaped string) (resourcePath, bool) {
+	rest, ok := strings.CutPrefix(escaped, Prefix)
+	if !ok {
+		return resourcePath{}, false
+	}
+	parts := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+
+	ownerID, err := url.PathUnescape(parts[0])
+	if err != nil || ownerID == "" {
+		return resourcePath{}, false
+	}
+	p := resourcePath{kind: kindPrincipal, ownerID: ownerID}
+
+	switch {
+	case len(parts) == 1:
+		return p, true
+	case parts[1] != calendarName:
+		return resourcePath{}, false
+	case len(parts) == 2:
+		p.kind = kindCalendar
+		return p, true
+	case len(parts) == 3:
+		name, ok := strings.CutSuffix(parts[2], ".ics")
+		if !ok {
+			return resourcePath{}, false
+		}
+		uid, err := url.PathUnescape(name)
+		if err != nil || uid == "" {
+			return resourcePath{}, false
+		}
+		p.kind = kindObject
+		p.uid = uid
+		return p, true
+	default:
+		return resourcePath{}, false
+	}
+}
+
+func principalHref(ownerID string) string {
+	return Prefix + url.PathEscape(ownerID) + "/"
+}
+
+func calendarHref(ownerID string) string {
+	return principalHref(ownerID) + calendarName + "/"
+}
+
+func objectHref(ownerID, uid string) string {
+	return calendarHref(ownerID) + url.PathEscape(uid) + ".ics"
+}
+
+// Ресурсы-события
+
+// object — ресурс .ics: событие или серия вместе с переопределениями вхождений.
+type object struct {
+	uid    string
+	events []repos.Event
+}
+
+// etag меняется при любом изменении события или его вхождений.
+func (o object) etag() string {
+	h := sha256.New()
+	for _, e := range o.events {
+		h.Write([]byte(e.ID + ":" + strconv.FormatInt(e.Version, 10) + ";"))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (o object) modTime() time.Time {
+	var t time.Time
+	for _, e := range o.events {
+		if e.UpdatedAt.After(t) {
+			t = e.UpdatedAt
+		}
+	}
+	return t
+}
+
+func (o object) data() (string, error) {
+	var buf strings.Builder
+	if err := ics.Encode(&buf, ics.NewObject(o.events)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// objects возвращает события, которыми владеет ownerID, сгруппированные по UID.
+// События, на которые пользователь только приглашён, в его коллекцию не попадают.
+func (h *Handler) objects(ctx context.Context, ownerID string) ([]object, error) {
+	events, err := h.events.ExportEvents(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		objects []object
+		index   = make(map[string]int)
+	)
+	for _, e := range events {
+		if e.OwnerID != ownerID {
+			continue
+		}
+		uid := ics.UID(e)
+		i, ok := index[uid]
+		if !ok {
+			i = len(objects)
+			index[uid] = i
+			objects = append(objects, object{uid: uid})
+		}
+		objects[i].events = append(objects[i].events, e)
+	}
+	return objects, nil
+}
+
+// ctag меняется при любом изменении в коллекции, в том числе при удалении события.
+func ctag(objects []object) string {
+	h := sha256.New()
+	for _, o := range objects {
+		h.Write([]byte(o.uid + "=" + o.etag() + ";"))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// Условные запросы
+
+// etagMatches проверяет заголовок If-Match / If-None-Match: список ETag или "*".
+// Пустой etag означает, что ресурса нет.
+func etagMatches(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" && etag != "" {
+			return true
+		}
+		if etag != "" && strings.TrimPrefix(v, "W/") == quote(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPreconditions сообщает, выполнены ли If-Match и If-None-Match для ресурса с etag.
+func checkPreconditions(r *http.Request, etag string) bool {
+	if v := r.Header.Get("If-Match"); v != "" && !etagMatches(v, etag) {
+		return false
+	}
+	if v := r.Header.Get("If-None-Match"); v != "" && etagMatches(v, etag) {
+		return false
+	}
+	return true
+}
+
+func quote(etag string) string {
+	return `"` + etag + `"`
+}
+
+// serviceError отвечает на ошибку сервиса событий: отказ в доступе — 401/403, остальное — 500.
+func (h *Handler) serviceError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnauthenticated):
+		// Без вызова клиенты (Apple Calendar, Thunderbird, DAVx5) не спросят пароль.
+		w.Header().Set("WWW-Authenticate", auth.BasicChallenge)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, services.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		h.internalError(w, r, msg, err)
+	}
+}
+
+func (h *Handler) internalError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	logger.FromContext(r.Context()).Error(msg, "err", err)
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
@@ -0,0 +1,211 @@
+package caldav
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"mime"
+	"net/http"
+
+	"calendar/internal/ics"
+	"calendar/internal/repos"
+	"calendar/internal/services"
+)
+
+var (
+	conditionValidData     = xml.Name{Space: nsCalDAV, Local: "valid-calendar-data"}
+	conditionValidObject   = xml.Name{Space: nsCalDAV, Local: "valid-calendar-object-resource"}
+	conditionMaxSize       = xml.Name{Space: nsCalDAV, Local: "max-resource-size"}
+	conditionNoUIDConflict = xml.Name{Space: nsCalDAV, Local: "no-uid-conflict"}
+)
+
+// options — OPTIONS: клиенты по заголовку DAV узнают, что это CalDAV.
+func (h *Handler) options(w http.ResponseWriter, p resourcePath) {
+	allow := "OPTIONS, PROPFIND, REPORT"
+	if p.kind == kindObject {
+		allow = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND"
+	}
+
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	w.Header().Set("Allow", allow)
+	w.WriteHeader(http.StatusOK)
+}
+
+// get — GET/HEAD события в формате iCalendar.
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, p resourcePath) {
+	if p.kind != kindObject {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	o, err := h.object(r.Context(), p.ownerID, p.uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.serviceError(w, r, "caldav get failed", err)
+		return
+	}
+
+	w.Header().Set("ETag", quote(o.etag()))
+	w.Header().Set("Last-Modified", o.modTime().UTC().Format(http.TimeFormat))
+	if v := r.Header.Get("If-None-Match"); v != "" && etagMatches(v, o.etag()) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, err := o.data()
+	if err != nil {
+		h.internalError(w, r, "caldav encode failed", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(data))
+}
+
+// put — PUT события: создаёт или заменяет серию вместе с переопределениями вхождений.
+// Данные нормализуются при сохранении, поэтому ETag в ответе не возвращается
+// (RFC 4791, 5.3.4) — клиент перечитает событие.
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, p resourcePath) {
+	if p.kind != kindObject {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mediaType, _, err := mime.ParseMediaType(ct); err != nil || mediaType != "text/calendar" {
+			writePrecondition(w, http.StatusUnsupportedMediaType, conditionValidData, "content type must be text/calendar")
+			return
+		}
+	}
+
+	entries, err := ics.Decode(http.MaxBytesReader(w, r.Body, maxResourceSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writePrecondition(w, http.StatusForbidden, conditionMaxSize, "calendar resource is too large")
+			return
+		}
+		writePrecondition(w, http.StatusForbidden, conditionValidData, err.Error())
+		return
+	}
+
+	events := make([]repos.Event, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Err != nil {
+			writePrecondition(w, http.StatusForbidden, conditionValidObject, entry.Err.Error())
+			return
+		}
+		if entry.Event.ICalUID != entries[0].Event.ICalUID {
+			writePrecondition(w, http.StatusForbidden, conditionValidObject, "all components must have the same UID")
+			return
+		}
+		events = append(events, entry.Event)
+	}
+	uid := events[0].ICalUID
+
+	ctx := r.Context()
+
+	// Ресурс называется по UID события; другой UID по тому же пути означал бы второй ресурс с тем же именем.
+	if uid != p.uid {
+		existing, err := h.object(ctx, p.ownerID, p.uid)
+		if err == nil && existing.uid != uid {
+			writePrecondition(w, http.StatusConflict, conditionNoUIDConflict, "resource already contains an event with another UID")
+			return
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			h.serviceError(w, r, "caldav put failed", err)
+			return
+		}
+	}
+
+	current, err := h.object(ctx, p.ownerID, uid)
+	exists := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.serviceError(w, r, "caldav put failed", err)
+		return
+	}
+	etag := ""
+	if exists {
+		etag = current.etag()
+	}
+	if !checkPreconditions(r, etag) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	results, err := h.events.ImportEvents(ctx, p.ownerID, events)
+	if err != nil {
+		h.serviceError(w, r, "caldav put failed", err)
+		return
+	}
+	for _, res := range results {
+		if res.Status == services.ImportSkipped && !errors.Is(res.Err, services.ErrUnchanged) {
+			writePrecondition(w, http.StatusForbidden, conditionValidObject, res.Err.Error())
+			return
+		}
+	}
+
+	// Вхождения, которых больше нет в ресурсе, возвращаются к расписанию серии. Сравниваются ID:
+	// «плавающее» время вхождений из ресурса сервис переносит в пояс календаря.
+	kept := make(map[string]bool)
+	for _, res := range results {
+		kept[res.EventID] = true
+	}
+	for _, e := range current.events {
+		if e.RecurringEventID == "" || kept[e.ID] {
+			continue
+		}
+		if err := h.events.DeleteEvent(ctx, e.ID, 0); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			h.serviceError(w, r, "caldav put failed", err)
+			return
+		}
+	}
+
+	if exists {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Location", objectHref(p.ownerID, uid))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// delete — DELETE события вместе со всеми его вхождениями.
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, p resourcePath) {
+	if p.kind != kindObject {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	o, err := h.object(r.Context(), p.ownerID, p.uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.serviceError(w, r, "caldav delete failed", err)
+		return
+	}
+	if !checkPreconditions(r, o.etag()) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	// Серия всегда первая; её удаление удаляет и переопределения. С If-Match серия удаляется,
+	// только если не изменилась после проверки ETag.
+	var version int64
+	if r.Header.Get("If-Match") != "" {
+		version = o.events[0].Version
+	}
+	if err := h.events.DeleteEvent(r.Context(), o.events[0].ID, version); errors.Is(err, repos.ErrVersionMismatch) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.serviceError(w, r, "caldav delete failed", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,230 @@
+package caldav
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/emersion/go-ical"
+
+	"calendar/internal/ics"
+	"calendar/internal/logger"
+)
+
+// maxRequestSize — предельный размер XML-тела PROPFIND и REPORT.
+const maxRequestSize = 1 << 20
+
+// props — свойства ресурса с уже вычисленными значениями.
+type props map[xml.Name]property
+
+// hiddenProps не возвращаются на allprop (RFC 4791, 9.6; RFC 3744, 5.4).
+var hiddenProps = map[xml.Name]bool{
+	propCalendarData:       true,
+	propPrivilegeSet:       true,
+	propSupportedReportSet: true,
+}
+
+func (p props) set(name xml.Name, text string) {
+	p[name] = property{XMLName: prefixed(name), Text: text}
+}
+
+func (p props) setXML(name xml.Name, inner string) {
+	p[name] = property{XMLName: prefixed(name), Inner: inner}
+}
+
+// response отвечает на запрос свойств names; nil — все свойства (allprop).
+func (p props) response(href string, names []xml.Name) response {
+	var found, missing []property
+	if names == nil {
+		for name, v := range p {
+			if !hiddenProps[name] {
+				found = append(found, v)
+			}
+		}
+		sort.Slice(found, func(i, j int) bool { return found[i].XMLName.Local < found[j].XMLName.Local })
+	}
+	for _, name := range names {
+		if v, ok := p[name]; ok {
+			found = append(found, v)
+		} else {
+			missing = append(missing, property{XMLName: prefixed(name)})
+		}
+	}
+
+	resp := response{Href: href}
+	if len(found) > 0 {
+		resp.Propstats = append(resp.Propstats, propstat{Props: found, Status: status(http.StatusOK)})
+	}
+	if len(missing) > 0 {
+		resp.Propstats = append(resp.Propstats, propstat{Props: missing, Status: status(http.StatusNotFound)})
+	}
+	return resp
+}
+
+// Права текущего пользователя (RFC 3744). Свойства коллекций менять нельзя, поэтому write-properties нет.
+const (
+	readPrivileges  = `<D:privilege><D:read/></D:privilege><D:privilege><D:read-current-user-privilege-set/></D:privilege>`
+	writePrivileges = `<D:privilege><D:write/></D:privilege><D:privilege><D:write-content/></D:privilege>` +
+		`<D:privilege><D:bind/></D:privilege><D:privilege><D:unbind/></D:privilege>`
+)
+
+func principalProps(ownerID string) props {
+	p := make(props)
+	p.setXML(propResourceType, `<D:collection/><D:principal/>`)
+	p.set(propDisplayName, ownerID)
+	p.setXML(propCurrentUserPrincipal, hrefXML(principalHref(ownerID)))
+	p.setXML(propPrincipalURL, hrefXML(principalHref(ownerID)))
+	p.setXML(propCalendarHomeSet, hrefXML(principalHref(ownerID)))
+	p.setXML(propPrivilegeSet, readPrivileges)
+	return p
+}
+
+func calendarProps(ownerID string, objects []object) props {
+	p := make(props)
+	p.setXML(propResourceType, `<D:collection/><C:calendar/>`)
+	p.set(propDisplayName, ownerID)
+	p.setXML(propCurrentUserPrincipal, hrefXML(principalHref(ownerID)))
+	p.setXML(propOwner, hrefXML(principalHref(ownerID)))
+	p.setXML(propPrivilegeSet, readPrivileges+writePrivileges)
+	p.setXML(propSupportedReportSet,
+		`<D:supported-report><D:report><C:calendar-query/></D:report></D:supported-report>`+
+			`<D:supported-report><D:report><C:calendar-multiget/></D:report></D:supported-report>`)
+	p.setXML(propSupportedComponentSet, `<C:comp name="`+ical.CompEvent+`"/>`)
+	p.setXML(propSupportedCalendarData, `<C:calendar-data content-type="`+ical.MIMEType+`" version="2.0"/>`)
+	p.set(propMaxResourceSize, strconv.Itoa(maxResourceSize))
+
+	tag := ctag(objects)
+	p.set(propGetCTag, tag)
+	p.set(propGetETag, quote(tag))
+	return p
+}
+
+// objectProps собирает свойства события; calendar-data — только если withData.
+func objectProps(o object, withData bool) (props, error) {
+	p := make(props)
+	p.setXML(propResourceType, ``)
+	p.set(propGetETag, quote(o.etag()))
+	p.set(propGetContentType, ical.MIMEType+"; charset=utf-8; component="+ical.CompEvent)
+	p.set(propGetLastModified, o.modTime().UTC().Format(http.TimeFormat))
+	p.setXML(propPrivilegeSet, readPrivileges+writePrivileges)
+
+	if withData {
+		data, err := o.data()
+		if err != nil {
+			return nil, err
+		}
+		p.set(propCalendarData, data)
+	}
+	return p, nil
+}
+
+func wants(names []xml.Name, name xml.Name) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readXML разбирает тело запроса в v; false — тело пустое.
+func readXML(r *http.Request, v any) (bool, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		return false, err
+	}
+	if len(body) == 0 {
+		return false, nil
+	}
+	return true, xml.Unmarshal(body, v)
+}
+
+// propfind — PROPFIND (RFC 4918, 9.1). Depth: infinity обрабатывается как 1.
+func (h *Handler) propfind(w http.ResponseWriter, r *http.Request, p resourcePath) {
+	var req propfindRequest
+	ok, err := readXML(r, &req)
+	if err != nil {
+		http.Error(w, "invalid propfind body", http.StatusBadRequest)
+		return
+	}
+
+	// Пустое тело, allprop и propname — все свойства.
+	var names []xml.Name
+	if ok && req.AllProp == nil && len(req.Prop) > 0 {
+		names = req.Prop
+	}
+	deep := r.Header.Get("Depth") != "0"
+
+	responses, err := h.propfindResponses(r.Context(), p, names, deep)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.serviceError(w, r, "caldav propfind failed", err)
+		return
+	}
+
+	if err := writeMultistatus(w, responses); err != nil {
+		logger.FromContext(r.Context()).Error("caldav write response failed", "err", err)
+	}
+}
+
+func (h *Handler) propfindResponses(ctx context.Context, p resourcePath, names []xml.Name, deep bool) ([]response, error) {
+	withData := wants(names, propCalendarData)
+
+	switch p.kind {
+	case kindPrincipal:
+		responses := []response{principalProps(p.ownerID).response(principalHref(p.ownerID), names)}
+		if deep {
+			objects, err := h.objects(ctx, p.ownerID)
+			if err != nil {
+				return nil, err
+			}
+			responses = append(responses, calendarProps(p.ownerID, objects).response(calendarHref(p.ownerID), names))
+		}
+		return responses, nil
+
+	case kindCalendar:
+		objects, err := h.objects(ctx, p.ownerID)
+		if err != nil {
+			return nil, err
+		}
+		responses := []response{calendarProps(p.ownerID, objects).response(calendarHref(p.ownerID), names)}
+		if deep {
+			for _, o := range objects {
+				op, err := objectProps(o, withData)
+				if err != nil {
+					return nil, err
+				}
+				responses = append(responses, op.response(objectHref(p.ownerID, o.uid), names))
+			}
+		}
+		return responses, nil
+
+	default:
+		o, err := h.object(ctx, p.ownerID, p.uid)
+		if err != nil {
+			return nil, err
+		}
+		op, err := objectProps(o, withData)
+		if err != nil {
+			return nil, err
+		}
+		return []response{op.response(objectHref(p.ownerID, o.uid), names)}, nil
+	}
+}
+
+// object загружает событие-ресурс по UID; если его нет — sql.ErrNoRows.
+func (h *Handler) object(ctx context.Context, ownerID, uid string) (object, error) {
+	events, err := h.events.GetEventsByUID(ctx, ownerID, uid)
+	if err != nil {
+		return object{}, err
+	}
+	return object{uid: ics.UID(events[0]), events: events}, nil
+}
@@ -0,0 +1,205 @@
+package caldav
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/emersion/go-ical"
+
+	"calendar/internal/logger"
+	"calendar/internal/services"
+)
+
+var (
+	reportCalendarQuery    = xml.Name{Space: nsCalDAV, Local: "calendar-query"}
+	reportCalendarMultiget = xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}
+
+	conditionSupportedReport = xml.Name{Space: nsDAV, Local: "supported-report"}
+	conditionValidFilter     = xml.Name{Space: nsCalDAV, Local: "valid-filter"}
+)
+
+// timeRangeFormat — формат атрибутов time-range (RFC 4791, 9.9): всегда UTC.
+const timeRangeFormat = "20060102T150405Z"
+
+// Границы окна, если у time-range нет start или end.
+var (
+	minTime = time.Time{}
+	maxTime = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// report — REPORT calendar-query и calendar-multiget над календарём пользователя.
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, p resourcePath) {
+	if p.kind != kindCalendar {
+		writePrecondition(w, http.StatusForbidden, conditionSupportedReport, "reports are supported on the calendar collection only")
+		return
+	}
+
+	var req reportRequest
+	if ok, err := readXML(r, &req); err != nil || !ok {
+		http.Error(w, "invalid report body", http.StatusBadRequest)
+		return
+	}
+
+	names := []xml.Name(req.Prop)
+	if req.AllProp != nil || len(names) == 0 {
+		names = []xml.Name{propGetETag, propCalendarData}
+	}
+	withData := wants(names, propCalendarData)
+
+	var (
+		responses []response
+		err       error
+	)
+	switch req.XMLName {
+	case reportCalendarQuery:
+		responses, err = h.calendarQuery(r, p.ownerID, req.Filter, names, withData)
+	case reportCalendarMultiget:
+		responses, err = h.calendarMultiget(r, p.ownerID, req.Hrefs, names, withData)
+	default:
+		writePrecondition(w, http.StatusForbidden, conditionSupportedReport, "unsupported report "+req.XMLName.Local)
+		return
+	}
+
+	var filterErr *invalidFilterError
+	if errors.As(err, &filterErr) {
+		writePrecondition(w, http.StatusBadRequest, conditionValidFilter, err.Error())
+		return
+	}
+	if err != nil {
+		h.serviceError(w, r, "caldav report failed", err)
+		return
+	}
+
+	if err := writeMultistatus(w, responses); err != nil {
+		logger.FromContext(r.Context()).Error("caldav write response failed", "err", err)
+	}
+}
+
+// calendarQuery возвращает события, подходящие под фильтр (RFC 4791, 7.8).
+// Поддерживаются comp-filter VCALENDAR/VEVENT и time-range; prop-filter игнорируются,
+// то есть выдача может быть шире запрошенной.
+func (h *Handler) calendarQuery(r *http.Request, ownerID string, filter *filterXML, names []xml.Name, withData bool) ([]response, error) {
+	match, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := h.objects(r.Context(), ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]response, 0, len(objects))
+	for _, o := range objects {
+		ok, err := match(o)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		op, err := objectProps(o, withData)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, op.response(objectHref(ownerID, o.uid), names))
+	}
+	return responses, nil
+}
+
+// calendarMultiget возвращает события по списку href (RFC 4791, 7.9).
+func (h *Handler) calendarMultiget(r *http.Request, ownerID string, hrefs []string, names []xml.Name, withData bool) ([]response, error) {
+	responses := make([]response, 0, len(hrefs))
+	for _, href := range hrefs {
+		u, err := url.Parse(href)
+		if err != nil {
+			responses = append(responses, response{Href: href, Status: status(http.StatusBadRequest)})
+			continue
+		}
+		p, ok := parsePath(u.EscapedPath())
+		if !ok || p.kind != kindObject || p.ownerID != ownerID {
+			responses = append(responses, response{Href: href, Status: status(http.StatusNotFound)})
+			continue
+		}
+
+		o, err := h.object(r.Context(), ownerID, p.uid)
+		if errors.Is(err, sql.ErrNoRows) {
+			responses = append(responses, response{Href: href, Status: status(http.StatusNotFound)})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		op, err := objectProps(o, withData)
+		if err != nil {
+			return nil, err
+		}
+		// Отвечаем тем же href, что прислал клиент: по нему он сопоставляет ответы.
+		responses = append(responses, op.response(href, names))
+	}
+	return responses, nil
+}
+
+type invalidFilterError struct {
+	msg string
+}
+
+func (e *invalidFilterError) Error() string {
+	return e.msg
+}
+
+// parseFilter строит проверку события по фильтру calendar-query.
+func parseFilter(filter *filterXML) (func(object) (bool, error), error) {
+	matchAll := func(object) (bool, error) { return true, nil }
+	matchNone := func(object) (bool, error) { return false, nil }
+
+	if filter == nil {
+		return matchAll, nil
+	}
+	if filter.Comp.Name != ical.CompCalendar {
+		return nil, &invalidFilterError{msg: "top-level comp-filter must be " + ical.CompCalendar}
+	}
+
+	type window struct{ from, to time.Time }
+	var windows []window
+	for _, c := range filter.Comp.Comps {
+		// Хранятся только VEVENT, поэтому фильтры по другим компонентам ничего не находят.
+		if c.Name != ical.CompEvent {
+			return matchNone, nil
+		}
+		if c.TimeRange == nil {
+			continue
+		}
+
+		from, to := minTime, maxTime
+		var err error
+		if c.TimeRange.Start != "" {
+			if from, err = time.Parse(timeRangeFormat, c.TimeRange.Start); err != nil {
+				return nil, &invalidFilterError{msg: fmt.Sprintf("invalid time-range start %q", c.TimeRange.Start)}
+			}
+		}
+		if c.TimeRange.End != "" {
+			if to, err = time.Parse(timeRangeFormat, c.TimeRange.End); err != nil {
+				return nil, &invalidFilterError{msg: fmt.Sprintf("invalid time-range end %q", c.TimeRange.End)}
+			}
+		}
+		windows = append(windows, window{from, to})
+	}
+
+	return func(o object) (bool, error) {
+		for _, win := range windows {
+			ok, err := services.OccursBetween(o.events, win.from, win.to)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}, nil
+}
@@ -0,0 +1,174 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+)
+
+// Пространства имён WebDAV (RFC 4918), CalDAV (RFC 4791) и расширений Apple CalendarServer (getctag).
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+)
+
+var (
+	propResourceType          = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName           = xml.Name{Space: nsDAV, Local: "displayname"}
+	propCurrentUserPrincipal  = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL          = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propOwner                 = xml.Name{Space: nsDAV, Local: "owner"}
+	propPrivilegeSet          = xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}
+	propSupportedReportSet    = xml.Name{Space: nsDAV, Local: "supported-report-set"}
+	propGetETag               = xml.Name{Space: nsDAV, Local: "getetag"}
+	propGetContentType        = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propGetLastModified       = xml.Name{Space: nsDAV, Local: "getlastmodified"}
+	propCalendarHomeSet       = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propCalendarData          = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propSupportedComponentSet = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propSupportedCalendarData = xml.Name{Space: nsCalDAV, Local: "supported-calendar-data"}
+	propMaxResourceSize       = xml.Name{Space: nsCalDAV, Local: "max-resource-size"}
+	propGetCTag               = xml.Name{Space: nsCS, Local: "getctag"}
+)
+
+// propList — имена свойств из <D:prop> запроса PROPFIND или REPORT.
+type propList []xml.Name
+
+// UnmarshalXML реализует xml.Unmarshaler.
+func (l *propList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			*l = append(*l, t.Name)
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+type propfindRequest struct {
+	XMLName xml.Name  `xml:"DAV: propfind"`
+	AllProp *struct{} `xml:"DAV: allprop"`
+	Prop    propList  `xml:"DAV: prop"`
+}
+
+// reportRequest — тело REPORT calendar-query или calendar-multiget (RFC 4791, 7.8–7.9).
+type reportRequest struct {
+	XMLName xml.Name
+	AllProp *struct{}  `xml:"DAV: allprop"`
+	Prop    propList   `xml:"DAV: prop"`
+	Hrefs   []string   `xml:"DAV: href"`
+	Filter  *filterXML `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+type filterXML struct {
+	Comp compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type compFilter struct {
+	Name      string       `xml:"name,attr"`
+	TimeRange *timeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	Comps     []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type timeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+// Ответ 207 Multi-Status. Известные пространства имён объявляются на корне с префиксами D, C и CS.
+
+type multistatus struct {
+	XMLName   xml.Name   `xml:"D:multistatus"`
+	NSDAV     string     `xml:"xmlns:D,attr"`
+	NSCalDAV  string     `xml:"xmlns:C,attr"`
+	NSCS      string     `xml:"xmlns:CS,attr"`
+	Responses []response `xml:"D:response"`
+}
+
+type response struct {
+	Href      string     `xml:"D:href"`
+	Propstats []propstat `xml:"D:propstat,omitempty"`
+	Status    string     `xml:"D:status,omitempty"`
+}
+
+type propstat struct {
+	Props  []property `xml:"D:prop>x"`
+	Status string     `xml:"D:status"`
+}
+
+// property — значение свойства: текст (экранируется) или готовый XML вложенных элементов.
+type property struct {
+	XMLName xml.Name
+	Text    string `xml:",chardata"`
+	Inner   string `xml:",innerxml"`
+}
+
+// prefixed переводит имя из известного пространства имён в имя с префиксом корня multistatus.
+func prefixed(name xml.Name) xml.Name {
+	switch name.Space {
+	case nsDAV:
+		return xml.Name{Local: "D:" + name.Local}
+	case nsCalDAV:
+		return xml.Name{Local: "C:" + name.Local}
+	case nsCS:
+		return xml.Name{Local: "CS:" + name.Local}
+	default:
+		return name
+	}
+}
+
+func status(code int) string {
+	return "HTTP/1.1 " + strconv.Itoa(code) + " " + http.StatusText(code)
+}
+
+// escape экранирует текст для вставки в Inner.
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func hrefXML(href string) string {
+	return "<D:href>" + escape(href) + "</D:href>"
+}
+
+func writeMultistatus(w http.ResponseWriter, responses []response) error {
+	ms := multistatus{
+		NSDAV:     nsDAV,
+		NSCalDAV:  nsCalDAV,
+		NSCS:      nsCS,
+		Responses: responses,
+	}
+
+	out, err := xml.Marshal(ms)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = w.Write([]byte(xml.Header))
+	_, err = w.Write(out)
+	return err
+}
+
+// writePrecondition отвечает ошибкой с элементом предусловия (RFC 4918, 16), например C:valid-calendar-data.
+func writePrecondition(w http.ResponseWriter, code int, condition xml.Name, msg string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(code)
+
+	name := prefixed(condition).Local
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write([]byte(`<D:error xmlns:D="` + nsDAV + `" xmlns:C="` + nsCalDAV + `"><` + name + `/>` +
+		`<D:responsedescription>` + escape(msg) + `</D:responsedescription></D:error>`))
+}
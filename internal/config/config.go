@@ -1,6 +1,11 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
 
 type HTTPServerConfig struct {
 	Host string `mapstructure:"host"`
@@ -19,6 +24,80 @@ type LoggingConfig struct {
 type KafkaConfig struct {
 	Brokers []string `mapstructure:"brokers"`
 	Topic   string   `mapstructure:"topic"`
+	// OutboxPollInterval — как часто producer проверяет outbox на неотправленные изменения.
+	OutboxPollInterval time.Duration `mapstructure:"outbox_poll_interval"`
+	// RemindersTopic — топик для сообщений reminder.due.
+	RemindersTopic string `mapstructure:"reminders_topic"`
+}
+
+type RemindersConfig struct {
+	// PollInterval — как часто планировщик ищет наступившие напоминания.
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// MissedGrace — насколько после начала события ещё можно отправить пропущенное
+	// напоминание (например, если сервис был остановлен в момент срабатывания).
+	MissedGrace time.Duration `mapstructure:"missed_grace"`
+}
+
+type AuthConfig struct {
+	// HMACSecret — общий секрет для токенов HS256, не короче 32 байт; пустой — такие токены
+	// не принимаются. В config.yaml не хранится: задаётся переменной окружения AUTH_HMAC_SECRET.
+	HMACSecret string `mapstructure:"hmac_secret"`
+	// JWKSFile — путь к JWKS с открытыми ключами RS256; пустой — такие токены не принимаются.
+	JWKSFile string `mapstructure:"jwks_file"`
+	// Issuer и Audience — ожидаемые iss и aud токена; пустые не проверяются.
+	Issuer   string `mapstructure:"issuer"`
+	Audience string `mapstructure:"audience"`
+	// Leeway — допустимое расхождение часов при проверке exp и nbf.
+	Leeway time.Duration `mapstructure:"leeway"`
+}
+
+type RateLimitRule struct {
+	// Name — название группы маршрутов; у каждой группы свои корзины.
+	Name string `mapstructure:"name"`
+	// Prefix — начало пути маршрутов группы, например "/api/events".
+	Prefix string `mapstructure:"prefix"`
+	// Methods — HTTP-методы группы; пустой список — все методы.
+	Methods []string `mapstructure:"methods"`
+	// Rate — сколько запросов в секунду в среднем разрешено одному клиенту.
+	Rate float64 `mapstructure:"rate"`
+	// Burst — сколько запросов клиент может сделать подряд (ёмкость корзины).
+	Burst int `mapstructure:"burst"`
+	// IPRate и IPBurst — лимит всех запросов с одного IP, который проверяется ещё до
+	// аутентификации; 0 — как Rate и Burst. За одним NAT бывает много пользователей,
+	// поэтому его обычно делают больше лимита одного клиента.
+	IPRate  float64 `mapstructure:"ip_rate"`
+	IPBurst int     `mapstructure:"ip_burst"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Groups — группы маршрутов; запрос попадает в первую подходящую, без группы не ограничивается.
+	Groups []RateLimitRule `mapstructure:"groups"`
+	// TrustedProxies — сколько своих прокси стоит перед сервисом. IP клиента берётся из
+	// X-Forwarded-For: это адрес, который дописал самый дальний из них (TrustedProxies-я запись
+	// справа); записи левее клиент может подделать. 0 — IP соединения.
+	TrustedProxies int `mapstructure:"trusted_proxies"`
+}
+
+type TracingConfig struct {
+	// Exporter — куда отправлять спаны: "otlp", "stdout", "file" или "none" (трассировка выключена).
+	Exporter string `mapstructure:"exporter"`
+	// Endpoint — адрес OTLP/HTTP коллектора, например "otel-collector:4318".
+	Endpoint string `mapstructure:"endpoint"`
+	// Insecure — отправлять в коллектор по HTTP без TLS.
+	Insecure bool `mapstructure:"insecure"`
+	// File — файл, в который экспортёр "file" дописывает спаны в JSON.
+	File string `mapstructure:"file"`
+	// SampleRatio — доля новых трасс, которые записываются (0..1); решение вызывающего
+	// сервиса из traceparent соблюдается всегда.
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+	// ServiceName — service.name в ресурсе спанов.
+	ServiceName string `mapstructure:"service_name"`
+}
+
+type HealthConfig struct {
+	// Timeout — сколько ждать каждую проверку готовности (/readyz), прежде чем считать компонент неготовым.
+	Timeout time.Duration `mapstructure:"timeout"`
 }
 
 type Config struct {
@@ -26,12 +105,19 @@ type Config struct {
 	Postgres   PostgresConfig   `mapstructure:"postgres"`
 	Logging    LoggingConfig    `mapstructure:"logging"`
 	Kafka      KafkaConfig      `mapstructure:"kafka"`
+	Reminders  RemindersConfig  `mapstructure:"reminders"`
+	Auth       AuthConfig       `mapstructure:"auth"`
+	RateLimit  RateLimitConfig  `mapstructure:"rate_limit"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Health     HealthConfig     `mapstructure:"health"`
 }
 
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
+	// Переменные окружения переопределяют ключи конфигурации: auth.hmac_secret — AUTH_HMAC_SECRET.
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	viper.SetDefault("http_server.host", "0.0.0.0")
@@ -40,6 +126,16 @@ func Load() (*Config, error) {
 	viper.SetDefault("postgres.auto_migrate", false)
 	viper.SetDefault("kafka.brokers", []string{"localhost:19092"})
 	viper.SetDefault("kafka.topic", "events")
+	viper.SetDefault("kafka.outbox_poll_interval", time.Second)
+	viper.SetDefault("kafka.reminders_topic", "reminders")
+	viper.SetDefault("reminders.poll_interval", 30*time.Second)
+	viper.SetDefault("reminders.missed_grace", 5*time.Minute)
+	viper.SetDefault("auth.hmac_secret", "")
+	viper.SetDefault("auth.leeway", 30*time.Second)
+	viper.SetDefault("tracing.exporter", "none")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("tracing.service_name", "calendar")
+	viper.SetDefault("health.timeout", 2*time.Second)
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
@@ -4,7 +4,9 @@ import (
     "database/sql"
     "fmt"
 
+    "github.com/XSAM/otelsql"
     _ "github.com/lib/pq"
+    semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
 
     "calendar/internal/config"
 )
@@ -14,7 +16,14 @@ type Postgres struct {
 }
 
 func NewPostgres(cfg *config.Config) (*Postgres, error) {
-    db, err := sql.Open("postgres", cfg.Postgres.DSN)
+    // Каждый запрос к базе — отдельный спан в трассе запроса, который его выполнил.
+    db, err := otelsql.Open("postgres", cfg.Postgres.DSN,
+        otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+        otelsql.WithSpanOptions(otelsql.SpanOptions{
+            OmitConnResetSession: true,
+            OmitRows:             true,
+        }),
+    )
     if err != nil {
         return nil, fmt.Errorf("failed to open postgres: %w", err)
     }
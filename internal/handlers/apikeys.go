@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"calendar/internal/logger"
+	"calendar/internal/repos"
+)
+
+// /api/keys/{id}
+func getAPIKeyIDFromPath(r *http.Request) string {
+	path := strings.TrimPrefix(r.URL.Path, "/api/keys/")
+	path = strings.Trim(path, "/")
+	if _, err := uuid.Parse(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// writeAPIKeyError отвечает на ошибку сервиса ключей API.
+func (h *Handlers) writeAPIKeyError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "api key not found")
+	case writeAccessError(w, err):
+	case isInvalidInput(err):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		logger.FromContext(r.Context()).Error(msg, "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+	}
+}
+
+// CreateAPIKey — POST /api/keys
+// Сам ключ возвращается только в этом ответе.
+func (h *Handlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	ownerID, ok := requestOwner(w, r, req.OwnerID)
+	if !ok {
+		return
+	}
+
+	k := &repos.APIKey{
+		ID:      uuid.New().String(),
+		OwnerID: ownerID,
+		Name:    req.Name,
+		Scopes:  req.Scopes,
+	}
+	key, err := h.events.CreateAPIKey(r.Context(), k)
+	if err != nil {
+		h.writeAPIKeyError(w, r, err, "create api key failed")
+		return
+	}
+
+	resp := toAPIKeyResponse(*k)
+	resp.Key = key
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// ListAPIKeys — GET /api/keys[?owner_id=...]
+func (h *Handlers) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ownerID, ok := requestOwner(w, r, r.URL.Query().Get("owner_id"))
+	if !ok {
+		return
+	}
+
+	keys, err := h.events.ListAPIKeys(r.Context(), ownerID)
+	if err != nil {
+		h.writeAPIKeyError(w, r, err, "list api keys failed")
+		return
+	}
+
+	resp := listAPIKeysResponse{
+		Keys: make([]apiKeyResponse, 0, len(keys)),
+	}
+	for _, k := range keys {
+		resp.Keys = append(resp.Keys, toAPIKeyResponse(k))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RevokeAPIKey — DELETE /api/keys/{id}
+func (h *Handlers) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := getAPIKeyIDFromPath(r)
+	if id == "" {
+		writeError(w, http.StatusNotFound, "api key not found")
+		return
+	}
+
+	if err := h.events.RevokeAPIKey(r.Context(), id); err != nil {
+		h.writeAPIKeyError(w, r, err, "revoke api key failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"calendar/internal/logger"
+	"calendar/internal/repos"
+)
+
+// /api/events/{id}/attendees[/{user}]
+// ok=false, если путь не относится к участникам события.
+func getAttendeePath(r *http.Request) (eventID, userID string, ok bool) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/events/")
+	path = strings.Trim(path, "/")
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "attendees" {
+		return "", "", false
+	}
+	if len(parts) == 3 {
+		userID = parts[2]
+	}
+	return parts[0], userID, true
+}
+
+// writeAttendeeError отвечает на ошибку сервиса участников.
+func (h *Handlers) writeAttendeeError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "attendee or event not found")
+	case writeAccessError(w, err):
+	case errors.Is(err, repos.ErrAttendeeExists):
+		writeError(w, http.StatusConflict, err.Error())
+	case isInvalidInput(err):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		logger.FromContext(r.Context()).Error(msg, "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+	}
+}
+
+// AddAttendee — POST /api/events/{id}/attendees
+func (h *Handlers) AddAttendee(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	eventID, _, _ := getAttendeePath(r)
+	if _, err := uuid.Parse(eventID); err != nil {
+		writeError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	var req addAttendeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	a := &repos.Attendee{
+		EventID: eventID,
+		UserID:  req.UserID,
+		Status:  req.Status,
+	}
+	if err := h.events.AddAttendee(r.Context(), a); err != nil {
+		h.writeAttendeeError(w, r, err, "add attendee failed")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toAttendeeResponse(*a))
+}
+
+// UpdateAttendee — PATCH /api/events/{id}/attendees/{user}
+func (h *Handlers) UpdateAttendee(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	eventID, userID, _ := getAttendeePath(r)
+	if _, err := uuid.Parse(eventID); err != nil {
+		writeError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	var req updateAttendeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	a := &repos.Attendee{
+		EventID: eventID,
+		UserID:  userID,
+		Status:  req.Status,
+	}
+	if err := h.events.UpdateAttendeeStatus(r.Context(), a); err != nil {
+		h.writeAttendeeError(w, r, err, "update attendee failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAttendeeResponse(*a))
+}
+
+// RemoveAttendee — DELETE /api/events/{id}/attendees/{user}
+func (h *Handlers) RemoveAttendee(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	eventID, userID, _ := getAttendeePath(r)
+	if _, err := uuid.Parse(eventID); err != nil {
+		writeError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	if err := h.events.RemoveAttendee(r.Context(), eventID, userID); err != nil {
+		h.writeAttendeeError(w, r, err, "remove attendee failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
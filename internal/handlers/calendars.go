@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"calendar/internal/ics"
+	"calendar/internal/logger"
+	"calendar/internal/repos"
+)
+
+// /api/calendars/{id}
+func getCalendarIDFromPath(r *http.Request) string {
+	path := strings.TrimPrefix(r.URL.Path, "/api/calendars/")
+	path = strings.Trim(path, "/")
+	if _, err := uuid.Parse(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// writeCalendarError отвечает на ошибку сервиса календарей.
+func (h *Handlers) writeCalendarError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "calendar not found")
+	case writeAccessError(w, err):
+	case errors.Is(err, repos.ErrDefaultCalendar):
+		writeError(w, http.StatusConflict, err.Error())
+	case isInvalidInput(err):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		logger.FromContext(r.Context()).Error(msg, "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+	}
+}
+
+// CreateCalendar — POST /api/calendars
+func (h *Handlers) CreateCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req createCalendarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	ownerID, ok := requestOwner(w, r, req.OwnerID)
+	if !ok {
+		return
+	}
+
+	c := &repos.Calendar{
+		ID:       uuid.New().String(),
+		OwnerID:  ownerID,
+		Name:     req.Name,
+		Color:    req.Color,
+		TimeZone: req.TimeZone,
+	}
+	if err := h.events.CreateCalendar(r.Context(), c); err != nil {
+		h.writeCalendarError(w, r, err, "create calendar failed")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toCalendarResponse(*c))
+}
+
+// ListCalendars — GET /api/calendars?owner_id=...
+func (h *Handlers) ListCalendars(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ownerID, ok := requestOwner(w, r, r.URL.Query().Get("owner_id"))
+	if !ok {
+		return
+	}
+
+	calendars, err := h.events.ListCalendars(r.Context(), ownerID)
+	if err != nil {
+		h.writeCalendarError(w, r, err, "list calendars failed")
+		return
+	}
+
+	resp := listCalendarsResponse{
+		Calendars: make([]calendarResponse, 0, len(calendars)),
+	}
+	for _, c := range calendars {
+		resp.Calendars = append(resp.Calendars, toCalendarResponse(c))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetCalendar — GET /api/calendars/{id}
+func (h *Handlers) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := getCalendarIDFromPath(r)
+	if id == "" {
+		writeError(w, http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	c, err := h.events.GetCalendar(r.Context(), id)
+	if err != nil {
+		h.writeCalendarError(w, r, err, "get calendar failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toCalendarResponse(*c))
+}
+
+// UpdateCalendar — PUT/PATCH /api/calendars/{id}
+func (h *Handlers) UpdateCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPatch {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := getCalendarIDFromPath(r)
+	if id == "" {
+		writeError(w, http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	var req updateCalendarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	update, fields, err := calendarUpdate(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c := &update
+	c.ID = id
+	if err := h.events.UpdateCalendar(r.Context(), c, fields); err != nil {
+		h.writeCalendarError(w, r, err, "update calendar failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toCalendarResponse(*c))
+}
+
+// DeleteCalendar — DELETE /api/calendars/{id}
+// Удаляет календарь вместе со всеми его событиями.
+func (h *Handlers) DeleteCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := getCalendarIDFromPath(r)
+	if id == "" {
+		writeError(w, http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	if err := h.events.DeleteCalendar(r.Context(), id); err != nil {
+		h.writeCalendarError(w, r, err, "delete calendar failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// /api/calendars/{owner_id}.ics
+func getCalendarOwnerFromPath(r *http.Request) string {
+	path := strings.TrimPrefix(r.URL.Path, "/api/calendars/")
+	ownerID, ok := strings.CutSuffix(path, ".ics")
+	if !ok || ownerID == "" || strings.Contains(ownerID, "/") {
+		return ""
+	}
+	return ownerID
+}
+
+// ExportCalendar — GET /api/calendars/{owner_id}.ics[?token=<ключ API>]
+// Отдаёт события пользователя в формате iCalendar; на этот URL (или webcal://...)
+// можно подписаться из Outlook, Apple Calendar или Thunderbird. Клиенты подписок не передают
+// Authorization, поэтому в URL подписки кладётся ключ с правом feed:read.
+func (h *Handlers) ExportCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ownerID := getCalendarOwnerFromPath(r)
+	if ownerID == "" {
+		writeError(w, http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	var (
+		events []repos.Event
+		err    error
+	)
+	if token := r.URL.Query().Get("token"); token != "" {
+		events, err = h.events.ExportFeed(r.Context(), ownerID, token)
+	} else {
+		events, err = h.events.ExportEvents(r.Context(), ownerID)
+	}
+	if err != nil {
+		if writeAccessError(w, err) {
+			return
+		}
+		logger.FromContext(r.Context()).Error("export calendar failed", "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := ics.Encode(&buf, ics.NewCalendar(ownerID, events)); err != nil {
+		logger.FromContext(r.Context()).Error("encode calendar failed", "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": ownerID + ".ics"}))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"calendar/internal/repos"
+)
+
+// eventETag — ETag события: его версия в кавычках. Версия растёт при каждом изменении события.
+// У переопределения вхождения к ней через точку добавляется версия серии: участники и другие
+// общие поля вхождения меняются вместе с серией.
+func eventETag(e repos.Event) string {
+	etag := strconv.FormatInt(e.Version, 10)
+	if e.SeriesVersion != 0 {
+		etag += "." + strconv.FormatInt(e.SeriesVersion, 10)
+	}
+	return `"` + etag + `"`
+}
+
+// ifMatchVersion разбирает If-Match запроса на изменение события: ожидаемая версия или 0,
+// если заголовка нет или он "*"; seriesVersion — версия серии из ETag переопределения вхождения
+// (иначе 0). ok=false — в заголовке нет ни одного ETag события (например, слабый W/"..."
+// или чужой формат) либо их несколько; такой запрос не может быть выполнен условно
+// и отклоняется с 412.
+func ifMatchVersion(r *http.Request) (version, seriesVersion int64, ok bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, 0, true
+	}
+
+	type etag struct{ version, seriesVersion int64 }
+	var etags []etag
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" {
+			return 0, 0, true
+		}
+		// If-Match сравнивает ETag строго, поэтому слабые ETag не подходят.
+		if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+			continue
+		}
+		versionPart, seriesPart, hasSeries := strings.Cut(v[1:len(v)-1], ".")
+		n, err := strconv.ParseInt(versionPart, 10, 64)
+		if err != nil || n <= 0 {
+			continue
+		}
+		var series int64
+		if hasSeries {
+			if series, err = strconv.ParseInt(seriesPart, 10, 64); err != nil || series <= 0 {
+				continue
+			}
+		}
+		etags = append(etags, etag{n, series})
+	}
+	if len(etags) != 1 {
+		return 0, 0, false
+	}
+	return etags[0].version, etags[0].seriesVersion, true
+}
+
+// ifNoneMatch сообщает, что If-None-Match запроса совпадает с etag, — клиенту хватит 304.
+func ifNoneMatch(r *http.Request, etag string) bool {
+	for _, v := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writePreconditionFailed отвечает 412 на изменение события, версия которого не совпала с If-Match.
+func writePreconditionFailed(w http.ResponseWriter) {
+	writeError(w, http.StatusPreconditionFailed, "event has been modified; fetch it again and retry")
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"calendar/internal/repos"
+)
+
+func TestEventETag(t *testing.T) {
+	if got := eventETag(repos.Event{Version: 42}); got != `"42"` {
+		t.Errorf("eventETag = %s, want \"42\"", got)
+	}
+	if got := eventETag(repos.Event{Version: 3, SeriesVersion: 42}); got != `"3.42"` {
+		t.Errorf("eventETag of an override = %s, want \"3.42\"", got)
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		wantVersion int64
+		wantSeries  int64
+		wantOK      bool
+	}{
+		{"no header", "", 0, 0, true},
+		{"any", "*", 0, 0, true},
+		{"strong etag", `"7"`, 7, 0, true},
+		{"spaces around", `  "7"  `, 7, 0, true},
+		{"any in a list", `"7", *`, 0, 0, true},
+		{"override etag", `"3.7"`, 3, 7, true},
+		{"weak etag", `W/"7"`, 0, 0, false},
+		{"unquoted", `7`, 0, 0, false},
+		{"half quoted", `"7`, 0, 0, false},
+		{"foreign format", `"abc"`, 0, 0, false},
+		{"zero version", `"0"`, 0, 0, false},
+		{"negative version", `"-1"`, 0, 0, false},
+		{"zero series version", `"3.0"`, 0, 0, false},
+		{"empty series version", `"3."`, 0, 0, false},
+		{"several versions", `"7", "8"`, 0, 0, false},
+		{"weak and strong", `W/"6", "7"`, 7, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/api/events/x", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+
+			version, seriesVersion, ok := ifMatchVersion(r)
+			if version != tt.wantVersion || seriesVersion != tt.wantSeries || ok != tt.wantOK {
+				t.Errorf("ifMatchVersion(%q) = %d, %d, %v; want %d, %d, %v",
+					tt.header, version, seriesVersion, ok, tt.wantVersion, tt.wantSeries, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	const etag = `"7"`
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"no header", "", false},
+		{"same etag", `"7"`, true},
+		{"weak comparison", `W/"7"`, true},
+		{"in a list", `"5", "7"`, true},
+		{"any", "*", true},
+		{"other version", `"8"`, false},
+		{"unquoted", `7`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/events/x", nil)
+			if tt.header != "" {
+				r.Header.Set("If-None-Match", tt.header)
+			}
+
+			if got := ifNoneMatch(r, etag); got != tt.want {
+				t.Errorf("ifNoneMatch(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,21 @@
 package handlers
 
 import (
+	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
+	"calendar/internal/logger"
 	"calendar/internal/repos"
+	"calendar/internal/services"
 )
 
 // /api/events/{id}
@@ -21,21 +28,158 @@ func getIDFromPath(r *http.Request) string {
 	return path
 }
 
-// CreateEvent — POST /api/events
+// getOccurrence читает вхождение серии из ?recurrence_id=...&scope=this|following.
+// Нулевой recurrenceID означает, что запрос относится ко всему событию.
+func getOccurrence(r *http.Request) (time.Time, services.RecurrenceScope, error) {
+	q := r.URL.Query()
+	if q.Get("recurrence_id") == "" {
+		return time.Time{}, "", nil
+	}
+
+	recurrenceID, err := time.Parse(time.RFC3339, q.Get("recurrence_id"))
+	if err != nil {
+		return time.Time{}, "", errors.New("invalid recurrence_id")
+	}
+	scope, err := services.ParseRecurrenceScope(q.Get("scope"))
+	if err != nil {
+		return time.Time{}, "", errors.New("invalid scope")
+	}
+	return recurrenceID, scope, nil
+}
+
+// getConflictPolicy читает из ?conflicts=allow|reject, что делать с пересечениями.
+func getConflictPolicy(r *http.Request) (services.ConflictPolicy, error) {
+	policy, err := services.ParseConflictPolicy(r.URL.Query().Get("conflicts"))
+	if err != nil {
+		return "", errors.New("invalid conflicts")
+	}
+	return policy, nil
+}
+
+// writeConflict отвечает 409 со списком событий, с которыми пересекается записываемое,
+// и временем пересечений с событиями, которые пользователь читать не может.
+func writeConflict(w http.ResponseWriter, err *services.ConflictError) {
+	resp := conflictResponse{
+		Error:     err.Error(),
+		Conflicts: make([]eventResponse, 0, len(err.Conflicts)),
+		Busy:      toIntervalResponses(err.Busy),
+	}
+	for _, e := range err.Conflicts {
+		resp.Conflicts = append(resp.Conflicts, toEventResponse(e))
+	}
+	writeJSON(w, http.StatusConflict, resp)
+}
+
+// getCalendarIDs читает фильтр по календарям из ?calendar_id=...: параметр можно повторять
+// или перечислять календари через запятую. nil — фильтра нет.
+func getCalendarIDs(r *http.Request) ([]string, error) {
+	var ids []string
+	for _, v := range r.URL.Query()["calendar_id"] {
+		for _, id := range strings.Split(v, ",") {
+			if _, err := uuid.Parse(id); err != nil {
+				return nil, errors.New("invalid calendar_id")
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// getPage читает параметры страницы из ?limit=...&cursor=...
+func getPage(r *http.Request) (int, *repos.Cursor, error) {
+	q := r.URL.Query()
+
+	limit := defaultListLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxListLimit {
+			return 0, nil, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+		}
+		limit = n
+	}
+
+	if q.Get("cursor") == "" {
+		return limit, nil, nil
+	}
+	after, err := decodeCursor(q.Get("cursor"))
+	if err != nil {
+		return 0, nil, errors.New("invalid cursor")
+	}
+	return limit, after, nil
+}
+
+// encodeCursor упаковывает позицию в непрозрачную для клиента строку.
+func encodeCursor(c repos.Cursor) string {
+	raw := c.StartTime.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*repos.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	start, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+	startTime, err := time.Parse(time.RFC3339Nano, start)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, err
+	}
+	return &repos.Cursor{StartTime: startTime, ID: id}, nil
+}
+
+// getWindow читает окно выборки из ?from=...&to=...; оба параметра задаются вместе.
+func getWindow(r *http.Request) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	if q.Get("from") == "" && q.Get("to") == "" {
+		return time.Time{}, time.Time{}, nil
+	}
+
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid from")
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid to")
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.New("from must be before to")
+	}
+	return from, to, nil
+}
+
+// CreateEvent — POST /api/events[?conflicts=allow|reject]
 func (h *Handlers) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
+	conflicts, err := getConflictPolicy(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var req createEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid json")
 		return
 	}
 
-	if req.Title == "" || req.OwnerID == "" {
-		writeError(w, http.StatusBadRequest, "title and owner_id are required")
+	if req.Title == "" {
+		writeError(w, http.StatusBadRequest, "title is required")
+		return
+	}
+	ownerID, ok := requestOwner(w, r, req.OwnerID)
+	if !ok {
 		return
 	}
 
@@ -50,69 +194,172 @@ func (h *Handlers) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	exdates, err := parseTimes(req.ExDates)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid exdates")
+		return
+	}
+	rdates, err := parseTimes(req.RDates)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid rdates")
+		return
+	}
+
+	if req.CalendarID != "" {
+		if _, err := uuid.Parse(req.CalendarID); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid calendar_id")
+			return
+		}
+	}
+
 	e := &repos.Event{
 		ID:          uuid.New().String(),
 		Title:       req.Title,
 		Description: req.Description,
 		StartTime:   start,
 		EndTime:     end,
-		OwnerID:     req.OwnerID,
+		OwnerID:     ownerID,
+		CalendarID:  req.CalendarID,
+		RRule:       req.RRule,
+		ExDates:     exdates,
+		RDates:      rdates,
+		Reminders:   req.Reminders,
+		TimeZone:    req.TimeZone,
 	}
 
-	if err := h.events.CreateEvent(r.Context(), e); err != nil {
-		h.log.Error("create event failed", "err", err)
+	if err := h.events.CreateEvent(r.Context(), e, conflicts); err != nil {
+		var conflict *services.ConflictError
+		if errors.As(err, &conflict) {
+			writeConflict(w, conflict)
+			return
+		}
+		if writeAccessError(w, err) {
+			return
+		}
+		if isInvalidInput(err) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("create event failed", "err", err)
 		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 
-	resp := eventResponse{
-		ID:          e.ID,
-		Title:       e.Title,
-		Description: e.Description,
-		StartTime:   e.StartTime.Format(time.RFC3339),
-		EndTime:     e.EndTime.Format(time.RFC3339),
-		OwnerID:     e.OwnerID,
-	}
-
-	writeJSON(w, http.StatusCreated, resp)
+	w.Header().Set("ETag", eventETag(*e))
+	writeJSON(w, http.StatusCreated, toEventResponse(*e))
 }
 
-// ListEvents — GET /api/events?owner_id=...
+// ListEvents — GET /api/events?owner_id=...[&calendar_id=...][&from=...&to=...][&limit=...&cursor=...]
 func (h *Handlers) ListEvents(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	ownerID := r.URL.Query().Get("owner_id")
-	if ownerID == "" {
-		writeError(w, http.StatusBadRequest, "owner_id is required")
+	ownerID, ok := requestOwner(w, r, r.URL.Query().Get("owner_id"))
+	if !ok {
 		return
 	}
 
-	events, err := h.events.ListEvents(r.Context(), ownerID)
+	calendarIDs, err := getCalendarIDs(r)
 	if err != nil {
-		h.log.Error("list events failed", "err", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	from, to, err := getWindow(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, after, err := getPage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.events.ListEvents(r.Context(), services.ListQuery{
+		OwnerID:     ownerID,
+		CalendarIDs: calendarIDs,
+		From:        from,
+		To:          to,
+		After:       after,
+		Limit:       limit,
+	})
+	if err != nil {
+		if writeAccessError(w, err) {
+			return
+		}
+		if isInvalidInput(err) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("list events failed", "err", err)
 		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 
-	resp := make([]eventResponse, 0, len(events))
-	for _, e := range events {
-		resp = append(resp, eventResponse{
-			ID:          e.ID,
-			Title:       e.Title,
-			Description: e.Description,
-			StartTime:   e.StartTime.Format(time.RFC3339),
-			EndTime:     e.EndTime.Format(time.RFC3339),
-			OwnerID:     e.OwnerID,
-		})
+	resp := listEventsResponse{
+		Events: make([]eventResponse, 0, len(page.Events)),
+	}
+	for _, e := range page.Events {
+		resp.Events = append(resp.Events, toEventResponse(e))
+	}
+	if page.Next != nil {
+		resp.NextCursor = encodeCursor(*page.Next)
 	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// UpdateEvent — PUT/PATCH /api/events/{id}
+// GetEvent — GET /api/events/{id}
+func (h *Handlers) GetEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := getIDFromPath(r)
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "id is required in path")
+		return
+	}
+
+	// Все ID событий — UUID; иначе запрос к БД упадёт на приведении типа.
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	e, err := h.events.GetEvent(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "event not found")
+			return
+		}
+		if writeAccessError(w, err) {
+			return
+		}
+		logger.FromContext(r.Context()).Error("get event failed", "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	etag := eventETag(*e)
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, toEventResponse(*e))
+}
+
+// UpdateEvent — PUT/PATCH /api/events/{id}[?recurrence_id=...&scope=this|following][&conflicts=allow|reject]
+// PATCH — JSON Merge Patch (Content-Type application/merge-patch+json или application/json, иначе 415),
+// PUT — замена события целиком (см. eventUpdate).
+// С If-Match событие (для вхождения — его серия, а с ETag переопределения — ещё и оно само) изменяется,
+// только если его ETag не изменился, иначе 412.
 func (h *Handlers) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut && r.Method != http.MethodPatch {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -124,6 +371,34 @@ func (h *Handlers) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "id is required in path")
 		return
 	}
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	recurrenceID, scope, err := getOccurrence(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conflicts, err := getConflictPolicy(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	version, seriesVersion, ok := ifMatchVersion(r)
+	if !ok {
+		writePreconditionFailed(w)
+		return
+	}
+
+	if r.Method == http.MethodPatch && !isMergePatch(r) {
+		w.Header().Set("Accept-Patch", acceptPatch)
+		writeError(w, http.StatusUnsupportedMediaType, "content type must be application/merge-patch+json or application/json")
+		return
+	}
 
 	var req updateEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -131,46 +406,62 @@ func (h *Handlers) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	e := &repos.Event{
-		ID: id,
+	replace := r.Method == http.MethodPut
+	patch, fields, err := eventUpdate(req, replace)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-
-	if req.Title != nil {
-		e.Title = *req.Title
+	// Переопределение вхождения не повторяется, поэтому PUT заменяет в нём всё, кроме правил повторения.
+	if replace && !recurrenceID.IsZero() && scope == services.ScopeThis {
+		fields &^= repos.RecurrenceFields
 	}
-	if req.Description != nil {
-		e.Description = *req.Description
+
+	e := &patch
+	e.ID = id
+	e.Version = version
+	e.SeriesVersion = seriesVersion
+
+	if recurrenceID.IsZero() {
+		err = h.events.UpdateEvent(r.Context(), e, fields, conflicts)
+	} else {
+		err = h.events.UpdateOccurrence(r.Context(), e, fields, recurrenceID, scope, conflicts)
 	}
-	if req.StartTime != nil {
-		start, err := time.Parse(time.RFC3339, *req.StartTime)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid start_time")
+	if err != nil {
+		var conflict *services.ConflictError
+		if errors.As(err, &conflict) {
+			writeConflict(w, conflict)
 			return
 		}
-		e.StartTime = start
-	}
-	if req.EndTime != nil {
-		end, err := time.Parse(time.RFC3339, *req.EndTime)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid end_time")
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "event not found")
 			return
 		}
-		e.EndTime = end
-	}
-	if req.OwnerID != nil {
-		e.OwnerID = *req.OwnerID
-	}
-
-	if err := h.events.UpdateEvent(r.Context(), e); err != nil {
-		h.log.Error("update event failed", "err", err)
+		if errors.Is(err, repos.ErrVersionMismatch) {
+			writePreconditionFailed(w)
+			return
+		}
+		if writeAccessError(w, err) {
+			return
+		}
+		if isInvalidInput(err) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("update event failed", "err", err)
 		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 
+	// Для вхождения новая версия серии неизвестна, поэтому ETag возвращается только для события целиком.
+	if recurrenceID.IsZero() {
+		w.Header().Set("ETag", eventETag(*e))
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// DeleteEvent — DELETE /api/events/{id}
+// DeleteEvent — DELETE /api/events/{id}[?recurrence_id=...&scope=this|following]
+// If-Match — как в UpdateEvent.
 func (h *Handlers) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -182,9 +473,49 @@ func (h *Handlers) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "id is required in path")
 		return
 	}
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	recurrenceID, scope, err := getOccurrence(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	version, seriesVersion, ok := ifMatchVersion(r)
+	if !ok {
+		writePreconditionFailed(w)
+		return
+	}
+	// Вхождение удаляется из серии, поэтому из ETag переопределения важна только версия серии.
+	if !recurrenceID.IsZero() && seriesVersion != 0 {
+		version = seriesVersion
+	}
 
-	if err := h.events.DeleteEvent(r.Context(), id); err != nil {
-		h.log.Error("delete event failed", "err", err)
+	if recurrenceID.IsZero() {
+		err = h.events.DeleteEvent(r.Context(), id, version)
+	} else {
+		err = h.events.DeleteOccurrence(r.Context(), id, recurrenceID, scope, version)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "event not found")
+			return
+		}
+		if errors.Is(err, repos.ErrVersionMismatch) {
+			writePreconditionFailed(w)
+			return
+		}
+		if writeAccessError(w, err) {
+			return
+		}
+		if isInvalidInput(err) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("delete event failed", "err", err)
 		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
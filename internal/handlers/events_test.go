@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"calendar/internal/repos"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor repos.Cursor
+	}{
+		{
+			name:   "utc",
+			cursor: repos.Cursor{StartTime: time.Date(2026, time.March, 1, 9, 0, 0, 0, time.UTC), ID: "6f1c1a52-8a44-4c5e-9f53-2d7b2f8a0c11"},
+		},
+		{
+			name:   "offset and nanoseconds",
+			cursor: repos.Cursor{StartTime: time.Date(2026, time.March, 1, 9, 0, 0, 123456789, time.FixedZone("", 3*3600)), ID: "0b7e2d2c-54a4-4d6b-8d9e-1f3f7b3c9e21"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(encodeCursor(tt.cursor))
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if !got.StartTime.Equal(tt.cursor.StartTime) || got.ID != tt.cursor.ID {
+				t.Errorf("decodeCursor = %+v, want %+v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("2026-03-01T09:00:00Z|x"))},
+		{"no separator", encode("2026-03-01T09:00:00Z")},
+		{"bad time", encode("yesterday|6f1c1a52-8a44-4c5e-9f53-2d7b2f8a0c11")},
+		{"bad id", encode("2026-03-01T09:00:00Z|42")},
+		{"empty", encode("")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c, err := decodeCursor(tt.cursor); err == nil {
+				t.Errorf("decodeCursor(%q) = %+v, want error", tt.cursor, *c)
+			}
+		})
+	}
+}
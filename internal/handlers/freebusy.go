@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"calendar/internal/logger"
+)
+
+// FreeBusy — POST /api/freebusy
+func (h *Handlers) FreeBusy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req freeBusyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	from, err := time.Parse(time.RFC3339, req.From)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid from")
+		return
+	}
+	to, err := time.Parse(time.RFC3339, req.To)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid to")
+		return
+	}
+
+	busy, err := h.events.FreeBusy(r.Context(), req.OwnerIDs, from, to)
+	if err != nil {
+		if writeAccessError(w, err) {
+			return
+		}
+		if isInvalidInput(err) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("free/busy failed", "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	resp := freeBusyResponse{
+		From: from.Format(time.RFC3339),
+		To:   to.Format(time.RFC3339),
+		Busy: make(map[string][]intervalResponse, len(busy)),
+	}
+	for ownerID, intervals := range busy {
+		resp.Busy[ownerID] = toIntervalResponses(intervals)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
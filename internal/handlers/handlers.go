@@ -2,20 +2,22 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
-	"calendar/internal/logger"
+	"calendar/internal/auth"
+	"calendar/internal/repos"
 	"calendar/internal/services"
 )
 
 type Handlers struct {
-	log    logger.Logger
 	events services.EventsService
 }
 
-func NewHandlers(log logger.Logger, events services.EventsService) *Handlers {
+func NewHandlers(events services.EventsService) *Handlers {
 	return &Handlers{
-		log:    log,
 		events: events,
 	}
 }
@@ -25,17 +27,35 @@ func NewHandlers(log logger.Logger, events services.EventsService) *Handlers {
 type createEventRequest struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
-	StartTime   string `json:"start_time"` // RFC3339
-	EndTime     string `json:"end_time"`   // RFC3339
-	OwnerID     string `json:"owner_id"`
+	StartTime   string `json:"start_time"`            // RFC3339
+	EndTime     string `json:"end_time"`              // RFC3339
+	OwnerID     string `json:"owner_id,omitempty"`    // по умолчанию — пользователь из токена
+	CalendarID  string `json:"calendar_id,omitempty"` // по умолчанию — календарь владельца по умолчанию
+
+	// Повторение (RFC 5545)
+	RRule   string   `json:"rrule,omitempty"`   // например, "FREQ=WEEKLY;BYDAY=MO"
+	ExDates []string `json:"exdates,omitempty"` // RFC3339
+	RDates  []string `json:"rdates,omitempty"`  // RFC3339
+	// TimeZone — часовой пояс IANA, в котором разворачивается серия; по умолчанию — пояс календаря
+	TimeZone string `json:"time_zone,omitempty"`
+
+	// Reminders — за сколько минут до начала напомнить, например [10, 1440]
+	Reminders []int `json:"reminders,omitempty"`
 }
 
+// updateEventRequest — тело PUT/PATCH события; см. eventUpdate.
 type updateEventRequest struct {
-	Title       *string `json:"title,omitempty"`
-	Description *string `json:"description,omitempty"`
-	StartTime   *string `json:"start_time,omitempty"` // RFC3339
-	EndTime     *string `json:"end_time,omitempty"`   // RFC3339
-	OwnerID     *string `json:"owner_id,omitempty"`
+	Title       optional[string]   `json:"title"`
+	Description optional[string]   `json:"description"`
+	StartTime   optional[string]   `json:"start_time"` // RFC3339
+	EndTime     optional[string]   `json:"end_time"`   // RFC3339
+	OwnerID     optional[string]   `json:"owner_id"`
+	CalendarID  optional[string]   `json:"calendar_id"`
+	RRule       optional[string]   `json:"rrule"`
+	ExDates     optional[[]string] `json:"exdates"` // RFC3339
+	RDates      optional[[]string] `json:"rdates"`  // RFC3339
+	Reminders   optional[[]int]    `json:"reminders"`
+	TimeZone    optional[string]   `json:"time_zone"`
 }
 
 type eventResponse struct {
@@ -45,10 +65,344 @@ type eventResponse struct {
 	StartTime   string `json:"start_time"`
 	EndTime     string `json:"end_time"`
 	OwnerID     string `json:"owner_id"`
+	CalendarID  string `json:"calendar_id"`
+
+	RRule            string   `json:"rrule,omitempty"`
+	ExDates          []string `json:"exdates,omitempty"`
+	RDates           []string `json:"rdates,omitempty"`
+	TimeZone         string   `json:"time_zone"`
+	RecurringEventID string   `json:"recurring_event_id,omitempty"`
+	RecurrenceID     string   `json:"recurrence_id,omitempty"`
+	Reminders        []int    `json:"reminders,omitempty"`
+
+	Attendees []attendeeResponse `json:"attendees"`
+}
+
+type createCalendarRequest struct {
+	OwnerID  string `json:"owner_id,omitempty"` // по умолчанию — пользователь из токена
+	Name     string `json:"name"`
+	Color    string `json:"color,omitempty"`     // "#rrggbb"
+	TimeZone string `json:"time_zone,omitempty"` // IANA, по умолчанию UTC
+}
+
+// updateCalendarRequest — тело PUT/PATCH календаря; см. calendarUpdate.
+type updateCalendarRequest struct {
+	Name     optional[string] `json:"name"`
+	Color    optional[string] `json:"color"`
+	TimeZone optional[string] `json:"time_zone"`
+}
+
+type calendarResponse struct {
+	ID        string `json:"id"`
+	OwnerID   string `json:"owner_id"`
+	Name      string `json:"name"`
+	Color     string `json:"color,omitempty"`
+	TimeZone  string `json:"time_zone"`
+	IsDefault bool   `json:"is_default"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type listCalendarsResponse struct {
+	Calendars []calendarResponse `json:"calendars"`
+}
+
+type shareRequest struct {
+	OwnerID    string `json:"owner_id,omitempty"`    // по умолчанию — пользователь из токена
+	CalendarID string `json:"calendar_id,omitempty"` // по умолчанию — все календари владельца
+	GranteeID  string `json:"grantee_id"`
+	Role       string `json:"role"` // free-busy / reader / writer / owner
+}
+
+type shareResponse struct {
+	ID         string `json:"id"`
+	OwnerID    string `json:"owner_id"`
+	CalendarID string `json:"calendar_id,omitempty"`
+	GranteeID  string `json:"grantee_id"`
+	Role       string `json:"role"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+type listSharesResponse struct {
+	Shares []shareResponse `json:"shares"`
+}
+
+type createAPIKeyRequest struct {
+	OwnerID string   `json:"owner_id,omitempty"` // по умолчанию — пользователь из токена
+	Name    string   `json:"name"`
+	Scopes  []string `json:"scopes"` // events:read / events:write / admin
+}
+
+type apiKeyResponse struct {
+	ID        string   `json:"id"`
+	OwnerID   string   `json:"owner_id"`
+	Name      string   `json:"name"`
+	Prefix    string   `json:"prefix"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt string   `json:"created_at"`
+	RevokedAt string   `json:"revoked_at,omitempty"`
+	// Key — сам ключ; возвращается только при выпуске.
+	Key string `json:"key,omitempty"`
+}
+
+type listAPIKeysResponse struct {
+	Keys []apiKeyResponse `json:"keys"`
+}
+
+type addAttendeeRequest struct {
+	UserID string `json:"user_id"`
+	Status string `json:"status,omitempty"` // по умолчанию needs-action
+}
+
+type updateAttendeeRequest struct {
+	Status string `json:"status"` // accepted / declined / tentative / needs-action
+}
+
+type attendeeResponse struct {
+	UserID    string `json:"user_id"`
+	Status    string `json:"status"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type importItemResponse struct {
+	UID          string `json:"uid,omitempty"`
+	RecurrenceID string `json:"recurrence_id,omitempty"`
+	Status       string `json:"status"` // created / updated / skipped
+	EventID      string `json:"event_id,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+type importEventsResponse struct {
+	Created int                  `json:"created"`
+	Updated int                  `json:"updated"`
+	Skipped int                  `json:"skipped"`
+	Items   []importItemResponse `json:"items"`
+}
+
+type conflictResponse struct {
+	Error     string          `json:"error"`
+	Conflicts []eventResponse `json:"conflicts"`
+	// Busy — пересечения с событиями, которые пользователь не может читать: только время.
+	Busy []intervalResponse `json:"busy"`
+}
+
+type freeBusyRequest struct {
+	OwnerIDs []string `json:"owner_ids"`
+	From     string   `json:"from"` // RFC3339
+	To       string   `json:"to"`   // RFC3339
+}
+
+type intervalResponse struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type freeBusyResponse struct {
+	From string                        `json:"from"`
+	To   string                        `json:"to"`
+	Busy map[string][]intervalResponse `json:"busy"`
+}
+
+type suggestRequest struct {
+	Attendees       []suggestAttendeeRequest `json:"attendees"`
+	DurationMinutes int                      `json:"duration_minutes"`
+	From            string                   `json:"from"` // RFC3339
+	To              string                   `json:"to"`   // RFC3339
+	StepMinutes     int                      `json:"step_minutes,omitempty"`
+	Limit           int                      `json:"limit,omitempty"`
+}
+
+type suggestAttendeeRequest struct {
+	UserID   string `json:"user_id"`
+	Optional bool   `json:"optional,omitempty"`
+	// WorkingHours — по умолчанию пн–пт 09:00–18:00 в поясе календаря участника по умолчанию
+	WorkingHours *workingHoursRequest `json:"working_hours,omitempty"`
+}
+
+type workingHoursRequest struct {
+	TimeZone string   `json:"time_zone,omitempty"` // IANA, например "Europe/Moscow"; по умолчанию — пояс календаря участника
+	Start    string   `json:"start"`               // "09:00"
+	End      string   `json:"end"`                 // "18:00"
+	Days     []string `json:"days,omitempty"`      // "mon" ... "sun"; по умолчанию пн–пт
+}
+
+type suggestionResponse struct {
+	Start               string   `json:"start"`
+	End                 string   `json:"end"`
+	Score               float64  `json:"score"`
+	Busy                []string `json:"busy"`
+	OutsideWorkingHours []string `json:"outside_working_hours"`
+}
+
+type suggestResponse struct {
+	Suggestions []suggestionResponse `json:"suggestions"`
+}
+
+type listEventsResponse struct {
+	Events     []eventResponse `json:"events"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// Размер страницы GET /api/events
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+func toEventResponse(e repos.Event) eventResponse {
+	resp := eventResponse{
+		ID:               e.ID,
+		Title:            e.Title,
+		Description:      e.Description,
+		StartTime:        e.StartTime.Format(time.RFC3339),
+		EndTime:          e.EndTime.Format(time.RFC3339),
+		OwnerID:          e.OwnerID,
+		CalendarID:       e.CalendarID,
+		RRule:            e.RRule,
+		ExDates:          formatTimes(e.ExDates),
+		RDates:           formatTimes(e.RDates),
+		TimeZone:         e.TimeZone,
+		RecurringEventID: e.RecurringEventID,
+		Reminders:        e.Reminders,
+	}
+	if !e.RecurrenceID.IsZero() {
+		resp.RecurrenceID = e.RecurrenceID.Format(time.RFC3339)
+	}
+	resp.Attendees = make([]attendeeResponse, 0, len(e.Attendees))
+	for _, a := range e.Attendees {
+		resp.Attendees = append(resp.Attendees, toAttendeeResponse(a))
+	}
+	return resp
+}
+
+func toIntervalResponses(intervals []services.Interval) []intervalResponse {
+	res := make([]intervalResponse, 0, len(intervals))
+	for _, i := range intervals {
+		res = append(res, intervalResponse{
+			Start: i.Start.Format(time.RFC3339),
+			End:   i.End.Format(time.RFC3339),
+		})
+	}
+	return res
+}
+
+func toCalendarResponse(c repos.Calendar) calendarResponse {
+	return calendarResponse{
+		ID:        c.ID,
+		OwnerID:   c.OwnerID,
+		Name:      c.Name,
+		Color:     c.Color,
+		TimeZone:  c.TimeZone,
+		IsDefault: c.IsDefault,
+		CreatedAt: c.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: c.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toShareResponse(sh repos.Share) shareResponse {
+	return shareResponse{
+		ID:         sh.ID,
+		OwnerID:    sh.OwnerID,
+		CalendarID: sh.CalendarID,
+		GranteeID:  sh.GranteeID,
+		Role:       sh.Role,
+		CreatedAt:  sh.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:  sh.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toAPIKeyResponse(k repos.APIKey) apiKeyResponse {
+	resp := apiKeyResponse{
+		ID:        k.ID,
+		OwnerID:   k.OwnerID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt.Format(time.RFC3339),
+	}
+	if k.RevokedAt != nil {
+		resp.RevokedAt = k.RevokedAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func toAttendeeResponse(a repos.Attendee) attendeeResponse {
+	return attendeeResponse{
+		UserID:    a.UserID,
+		Status:    a.Status,
+		UpdatedAt: a.UpdatedAt.Format(time.RFC3339),
+	}
 }
 
 // Вспомогалки
 
+func parseTimes(values []string) ([]time.Time, error) {
+	res := make([]time.Time, 0, len(values))
+	for _, v := range values {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, nil
+}
+
+func formatTimes(times []time.Time) []string {
+	if len(times) == 0 {
+		return nil
+	}
+	res := make([]string, 0, len(times))
+	for _, t := range times {
+		res = append(res, t.Format(time.RFC3339))
+	}
+	return res
+}
+
+// isInvalidInput сообщает, что сервис отверг событие как некорректное.
+func isInvalidInput(err error) bool {
+	return errors.Is(err, services.ErrInvalidRecurrence) ||
+		errors.Is(err, services.ErrNotRecurring) ||
+		errors.Is(err, services.ErrNoSuchOccurrence) ||
+		errors.Is(err, services.ErrInvalidTimeZone) ||
+		errors.Is(err, services.ErrInvalidReminders) ||
+		errors.Is(err, services.ErrInvalidTimeRange) ||
+		errors.Is(err, services.ErrInvalidFreeBusy) ||
+		errors.Is(err, services.ErrInvalidSuggestQuery) ||
+		errors.Is(err, services.ErrInvalidAttendee) ||
+		errors.Is(err, services.ErrInvalidCalendar) ||
+		errors.Is(err, services.ErrInvalidShare) ||
+		errors.Is(err, services.ErrInvalidAPIKey)
+}
+
+// writeAccessError отвечает 401 или 403, если сервис отказал в доступе, и сообщает, записан ли ответ.
+func writeAccessError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrUnauthenticated):
+		writeError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrForbidden):
+		writeError(w, http.StatusForbidden, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// requestOwner возвращает владельца календарей, к которым обращается запрос: явно указанный
+// owner_id (чужие календари — доступ проверяет сервис) или пользователя из токена.
+// Если нет ни того ни другого, отвечает 401.
+func requestOwner(w http.ResponseWriter, r *http.Request, ownerID string) (string, bool) {
+	if ownerID != "" {
+		return ownerID, true
+	}
+	if userID, ok := auth.UserFrom(r.Context()); ok {
+		return userID, true
+	}
+	writeError(w, http.StatusUnauthorized, services.ErrUnauthenticated.Error())
+	return "", false
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -74,9 +428,28 @@ func (h *Handlers) RegisterRoutes(mux *http.ServeMux) {
 		}
 	})
 
-	// обновление/удаление по id
+	// импорт из iCalendar
+	mux.HandleFunc("/api/events/import", h.ImportEvents)
+
+	// чтение/обновление/удаление по id и участники события
 	mux.HandleFunc("/api/events/", func(w http.ResponseWriter, r *http.Request) {
+		if _, userID, ok := getAttendeePath(r); ok {
+			switch {
+			case r.Method == http.MethodPost && userID == "":
+				h.AddAttendee(w, r)
+			case r.Method == http.MethodPatch && userID != "":
+				h.UpdateAttendee(w, r)
+			case r.Method == http.MethodDelete && userID != "":
+				h.RemoveAttendee(w, r)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		switch r.Method {
+		case http.MethodGet:
+			h.GetEvent(w, r)
 		case http.MethodPut, http.MethodPatch:
 			h.UpdateEvent(w, r)
 		case http.MethodDelete:
@@ -85,4 +458,67 @@ func (h *Handlers) RegisterRoutes(mux *http.ServeMux) {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
 	})
+
+	// занятость пользователей
+	mux.HandleFunc("/api/freebusy", h.FreeBusy)
+
+	// подбор времени встречи
+	mux.HandleFunc("/api/scheduling/suggest", h.SuggestTimes)
+
+	// календари пользователя
+	mux.HandleFunc("/api/calendars", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.CreateCalendar(w, r)
+		case http.MethodGet:
+			h.ListCalendars(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	// доступы к календарям
+	mux.HandleFunc("/api/shares", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.ShareAccess(w, r)
+		case http.MethodGet:
+			h.ListShares(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/shares/", h.RevokeAccess)
+
+	// ключи API
+	mux.HandleFunc("/api/keys", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.CreateAPIKey(w, r)
+		case http.MethodGet:
+			h.ListAPIKeys(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/keys/", h.RevokeAPIKey)
+
+	// календарь по id и выгрузка календаря пользователя в iCalendar
+	mux.HandleFunc("/api/calendars/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".ics") {
+			h.ExportCalendar(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			h.GetCalendar(w, r)
+		case http.MethodPut, http.MethodPatch:
+			h.UpdateCalendar(w, r)
+		case http.MethodDelete:
+			h.DeleteCalendar(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
 }
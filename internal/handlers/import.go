@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"calendar/internal/ics"
+	"calendar/internal/logger"
+	"calendar/internal/repos"
+	"calendar/internal/services"
+)
+
+// maxImportSize — предельный размер тела POST /api/events/import.
+const maxImportSize = 10 << 20
+
+// ImportEvents — POST /api/events/import?owner_id=...
+// Принимает календарь text/calendar и создаёт или обновляет его события (по UID)
+// в календаре owner_id. В ответе — итог по каждому VEVENT.
+func (h *Handlers) ImportEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ownerID, ok := requestOwner(w, r, r.URL.Query().Get("owner_id"))
+	if !ok {
+		return
+	}
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != "text/calendar" {
+			writeError(w, http.StatusUnsupportedMediaType, "content type must be text/calendar")
+			return
+		}
+	}
+
+	entries, err := ics.Decode(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "calendar is too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid calendar: "+err.Error())
+		return
+	}
+
+	// Разобранные события уходят в сервис, неразобранные сразу считаются пропущенными.
+	var events []repos.Event
+	for _, entry := range entries {
+		if entry.Err == nil {
+			events = append(events, entry.Event)
+		}
+	}
+
+	results, err := h.events.ImportEvents(r.Context(), ownerID, events)
+	if err != nil {
+		if writeAccessError(w, err) {
+			return
+		}
+		logger.FromContext(r.Context()).Error("import events failed", "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	resp := importEventsResponse{
+		Items: make([]importItemResponse, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		item := importItemResponse{
+			UID: entry.Event.ICalUID,
+		}
+		if !entry.Event.RecurrenceID.IsZero() {
+			item.RecurrenceID = entry.Event.RecurrenceID.Format(time.RFC3339)
+			if entry.Event.Floating {
+				// Местное время без пояса: в каком поясе оно окажется, решает календарь события.
+				item.RecurrenceID = entry.Event.RecurrenceID.Format("2006-01-02T15:04:05")
+			}
+		}
+
+		if entry.Err != nil {
+			item.Status = string(services.ImportSkipped)
+			item.Reason = entry.Err.Error()
+		} else {
+			res := results[0]
+			results = results[1:]
+			item.Status = string(res.Status)
+			item.EventID = res.EventID
+			if res.Err != nil {
+				item.Reason = res.Err.Error()
+			}
+		}
+
+		switch services.ImportStatus(item.Status) {
+		case services.ImportCreated:
+			resp.Created++
+		case services.ImportUpdated:
+			resp.Updated++
+		default:
+			resp.Skipped++
+		}
+		resp.Items = append(resp.Items, item)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"calendar/internal/repos"
+)
+
+// optional — поле тела PUT/PATCH: Set — поле есть в теле, Null — его значение null.
+type optional[T any] struct {
+	Value T
+	Set   bool
+	Null  bool
+}
+
+func (o *optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// acceptPatch — типы тела PATCH события: JSON Merge Patch или обычный JSON с той же семантикой.
+const acceptPatch = "application/merge-patch+json, application/json"
+
+// isMergePatch сообщает, что тело PATCH — JSON Merge Patch (или просто JSON). Другие форматы
+// патчей (например, JSON Patch, RFC 6902) разобрались бы как объект с неизвестными полями
+// и молча ничего бы не изменили.
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/merge-patch+json" || mediaType == "application/json")
+}
+
+// eventUpdate переводит тело запроса в изменения события и набор изменяемых полей.
+//
+// PATCH — JSON Merge Patch (RFC 7396): меняются только поля из тела, null очищает поле
+// (description, rrule, exdates, rdates, reminders; time_zone — возвращает пояс календаря),
+// а calendar_id: null переносит событие в календарь владельца по умолчанию. title, start_time, end_time и owner_id очистить нельзя.
+//
+// PUT (replace) заменяет содержимое события целиком: title, start_time и end_time обязательны,
+// отсутствующие необязательные поля очищаются. Владелец и календарь меняются, только если
+// указаны в теле.
+func eventUpdate(req updateEventRequest, replace bool) (repos.Event, repos.EventFields, error) {
+	var (
+		e      repos.Event
+		fields repos.EventFields
+	)
+	if replace {
+		fields = repos.ContentFields
+		if !req.Title.Set || !req.StartTime.Set || !req.EndTime.Set {
+			return repos.Event{}, 0, errors.New("title, start_time and end_time are required")
+		}
+	}
+
+	if req.Title.Set {
+		if req.Title.Value == "" {
+			return repos.Event{}, 0, errors.New("title is required")
+		}
+		e.Title = req.Title.Value
+		fields |= repos.FieldTitle
+	}
+	if req.Description.Set {
+		e.Description = req.Description.Value
+		fields |= repos.FieldDescription
+	}
+	if req.StartTime.Set {
+		start, err := time.Parse(time.RFC3339, req.StartTime.Value)
+		if err != nil {
+			return repos.Event{}, 0, errors.New("invalid start_time")
+		}
+		e.StartTime = start
+		fields |= repos.FieldStartTime
+	}
+	if req.EndTime.Set {
+		end, err := time.Parse(time.RFC3339, req.EndTime.Value)
+		if err != nil {
+			return repos.Event{}, 0, errors.New("invalid end_time")
+		}
+		e.EndTime = end
+		fields |= repos.FieldEndTime
+	}
+	if req.OwnerID.Set {
+		if req.OwnerID.Value == "" {
+			return repos.Event{}, 0, errors.New("owner_id cannot be cleared")
+		}
+		e.OwnerID = req.OwnerID.Value
+		fields |= repos.FieldOwnerID
+	}
+	if req.CalendarID.Set {
+		if !req.CalendarID.Null {
+			if _, err := uuid.Parse(req.CalendarID.Value); err != nil {
+				return repos.Event{}, 0, errors.New("invalid calendar_id")
+			}
+		}
+		e.CalendarID = req.CalendarID.Value
+		fields |= repos.FieldCalendarID
+	}
+	if req.RRule.Set {
+		e.RRule = req.RRule.Value
+		fields |= repos.FieldRRule
+	}
+	if req.ExDates.Set {
+		exdates, err := parseTimes(req.ExDates.Value)
+		if err != nil {
+			return repos.Event{}, 0, errors.New("invalid exdates")
+		}
+		e.ExDates = exdates
+		fields |= repos.FieldExDates
+	}
+	if req.RDates.Set {
+		rdates, err := parseTimes(req.RDates.Value)
+		if err != nil {
+			return repos.Event{}, 0, errors.New("invalid rdates")
+		}
+		e.RDates = rdates
+		fields |= repos.FieldRDates
+	}
+	if req.Reminders.Set {
+		e.Reminders = req.Reminders.Value
+		fields |= repos.FieldReminders
+	}
+	if req.TimeZone.Set {
+		e.TimeZone = req.TimeZone.Value
+		fields |= repos.FieldTimeZone
+	}
+	return e, fields, nil
+}
+
+// calendarUpdate переводит тело PUT/PATCH календаря в изменения календаря и набор изменяемых
+// полей: меняются только поля из тела, "color": null (или "") снимает цвет. name и time_zone
+// очистить нельзя.
+func calendarUpdate(req updateCalendarRequest) (repos.Calendar, repos.CalendarFields, error) {
+	var (
+		c      repos.Calendar
+		fields repos.CalendarFields
+	)
+	if req.Name.Null || req.TimeZone.Null {
+		return repos.Calendar{}, 0, errors.New("name and time_zone cannot be cleared")
+	}
+
+	if req.Name.Set {
+		c.Name = req.Name.Value
+		fields |= repos.FieldCalendarName
+	}
+	if req.Color.Set {
+		c.Color = req.Color.Value
+		fields |= repos.FieldCalendarColor
+	}
+	if req.TimeZone.Set {
+		c.TimeZone = req.TimeZone.Value
+		fields |= repos.FieldCalendarTimeZone
+	}
+	return c, fields, nil
+}
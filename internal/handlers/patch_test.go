@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"calendar/internal/repos"
+)
+
+func TestOptionalUnmarshal(t *testing.T) {
+	type body struct {
+		Description optional[string] `json:"description"`
+		Reminders   optional[[]int]  `json:"reminders"`
+	}
+
+	tests := []struct {
+		name            string
+		json            string
+		wantDescription optional[string]
+		wantReminders   optional[[]int]
+		wantErr         bool
+	}{
+		{name: "absent", json: `{}`},
+		{
+			name:            "null",
+			json:            `{"description": null, "reminders": null}`,
+			wantDescription: optional[string]{Set: true, Null: true},
+			wantReminders:   optional[[]int]{Set: true, Null: true},
+		},
+		{
+			name:            "empty values are not null",
+			json:            `{"description": "", "reminders": []}`,
+			wantDescription: optional[string]{Set: true},
+			wantReminders:   optional[[]int]{Value: []int{}, Set: true},
+		},
+		{
+			name:            "values",
+			json:            `{"description": "agenda", "reminders": [10, 60]}`,
+			wantDescription: optional[string]{Value: "agenda", Set: true},
+			wantReminders:   optional[[]int]{Value: []int{10, 60}, Set: true},
+		},
+		{name: "wrong type", json: `{"reminders": "soon"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b body
+			err := json.Unmarshal([]byte(tt.json), &b)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Unmarshal(%s) = %+v, want an error", tt.json, b)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+
+			if b.Description != tt.wantDescription {
+				t.Errorf("description = %+v, want %+v", b.Description, tt.wantDescription)
+			}
+			got, want := b.Reminders, tt.wantReminders
+			if got.Set != want.Set || got.Null != want.Null || !slices.Equal(got.Value, want.Value) ||
+				(got.Value == nil) != (want.Value == nil) {
+				t.Errorf("reminders = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEventUpdate(t *testing.T) {
+	const times = `"start_time": "2026-03-01T09:00:00Z", "end_time": "2026-03-01T10:00:00Z"`
+
+	tests := []struct {
+		name       string
+		json       string
+		replace    bool
+		wantFields repos.EventFields
+		wantErr    bool
+	}{
+		{name: "empty patch changes nothing", json: `{}`},
+		{name: "patch sets a field", json: `{"description": "agenda"}`, wantFields: repos.FieldDescription},
+		{name: "patch clears a field with null", json: `{"rrule": null, "exdates": null}`, wantFields: repos.FieldRRule | repos.FieldExDates},
+		{name: "patch null time zone", json: `{"time_zone": null}`, wantFields: repos.FieldTimeZone},
+		{name: "patch null calendar", json: `{"calendar_id": null}`, wantFields: repos.FieldCalendarID},
+		{name: "patch cannot clear the title", json: `{"title": null}`, wantErr: true},
+		{name: "patch cannot clear the owner", json: `{"owner_id": null}`, wantErr: true},
+		{name: "patch with a bad start time", json: `{"start_time": "tomorrow"}`, wantErr: true},
+		{name: "patch with a bad calendar", json: `{"calendar_id": "main"}`, wantErr: true},
+		{
+			name:       "put replaces the content",
+			json:       `{"title": "Standup", ` + times + `}`,
+			replace:    true,
+			wantFields: repos.ContentFields,
+		},
+		{
+			name:       "put changes the owner only when given",
+			json:       `{"title": "Standup", "owner_id": "user-2", ` + times + `}`,
+			replace:    true,
+			wantFields: repos.ContentFields | repos.FieldOwnerID,
+		},
+		{name: "put requires the title", json: `{` + times + `}`, replace: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req updateEventRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+
+			e, fields, err := eventUpdate(req, tt.replace)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("eventUpdate = %+v, %b; want an error", e, fields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("eventUpdate: %v", err)
+			}
+			if fields != tt.wantFields {
+				t.Errorf("fields = %b, want %b", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestEventUpdateClearsValues(t *testing.T) {
+	var req updateEventRequest
+	body := `{"description": null, "rrule": null, "exdates": null, "reminders": null}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+
+	e, _, err := eventUpdate(req, false)
+	if err != nil {
+		t.Fatalf("eventUpdate: %v", err)
+	}
+	if e.Description != "" || e.RRule != "" || len(e.ExDates) != 0 || len(e.Reminders) != 0 {
+		t.Errorf("eventUpdate = %+v, want cleared fields", e)
+	}
+}
+
+func TestIsMergePatch(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/merge-patch+json", true},
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"Application/JSON", true},
+		{"", false},
+		{"application/json-patch+json", false},
+		{"application/x-www-form-urlencoded", false},
+		{"text/plain", false},
+		{"application/json; charset", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/api/events/x", nil)
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			if got := isMergePatch(r); got != tt.want {
+				t.Errorf("isMergePatch(%q) = %v, want %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendarUpdate(t *testing.T) {
+	tests := []struct {
+		name       string
+		json       string
+		wantColor  string
+		wantFields repos.CalendarFields
+		wantErr    bool
+	}{
+		{name: "empty patch changes nothing", json: `{}`},
+		{name: "patch sets the color", json: `{"color": "#ff0000"}`, wantColor: "#ff0000", wantFields: repos.FieldCalendarColor},
+		{name: "null clears the color", json: `{"color": null}`, wantFields: repos.FieldCalendarColor},
+		{name: "empty color clears it too", json: `{"color": ""}`, wantFields: repos.FieldCalendarColor},
+		{
+			name:       "several fields",
+			json:       `{"name": "Work", "time_zone": "Europe/Berlin"}`,
+			wantFields: repos.FieldCalendarName | repos.FieldCalendarTimeZone,
+		},
+		{name: "name cannot be cleared", json: `{"name": null}`, wantErr: true},
+		{name: "time zone cannot be cleared", json: `{"time_zone": null}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req updateCalendarRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+
+			c, fields, err := calendarUpdate(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("calendarUpdate = %+v, %b; want an error", c, fields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("calendarUpdate: %v", err)
+			}
+			if fields != tt.wantFields || c.Color != tt.wantColor {
+				t.Errorf("calendarUpdate = color %q, fields %b; want %q, %b", c.Color, fields, tt.wantColor, tt.wantFields)
+			}
+		})
+	}
+}
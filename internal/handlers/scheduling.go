@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"calendar/internal/logger"
+	"calendar/internal/services"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseWorkingHours переводит рабочее время из запроса; nil — services.DefaultWorkingHours.
+func parseWorkingHours(req *workingHoursRequest) (services.WorkingHours, error) {
+	if req == nil {
+		return services.DefaultWorkingHours, nil
+	}
+
+	wh := services.WorkingHours{
+		Days: services.DefaultWorkingHours.Days,
+	}
+	if req.TimeZone != "" {
+		loc, err := time.LoadLocation(req.TimeZone)
+		if err != nil {
+			return wh, fmt.Errorf("unknown time_zone %q", req.TimeZone)
+		}
+		wh.Location = loc
+	}
+
+	var err error
+	if wh.Start, err = parseClock(req.Start); err != nil {
+		return wh, fmt.Errorf("invalid working_hours start %q", req.Start)
+	}
+	if wh.End, err = parseClock(req.End); err != nil {
+		return wh, fmt.Errorf("invalid working_hours end %q", req.End)
+	}
+
+	if len(req.Days) > 0 {
+		wh.Days = make([]time.Weekday, 0, len(req.Days))
+		for _, d := range req.Days {
+			day, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return wh, fmt.Errorf("invalid working_hours day %q", d)
+			}
+			wh.Days = append(wh.Days, day)
+		}
+	}
+	return wh, nil
+}
+
+// parseClock переводит "15:04" (или "24:00") в минуты от полуночи.
+func parseClock(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// SuggestTimes — POST /api/scheduling/suggest
+func (h *Handlers) SuggestTimes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req suggestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	from, err := time.Parse(time.RFC3339, req.From)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid from")
+		return
+	}
+	to, err := time.Parse(time.RFC3339, req.To)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid to")
+		return
+	}
+
+	q := services.SuggestQuery{
+		Attendees: make([]services.SuggestAttendee, 0, len(req.Attendees)),
+		Duration:  time.Duration(req.DurationMinutes) * time.Minute,
+		From:      from,
+		To:        to,
+		Step:      time.Duration(req.StepMinutes) * time.Minute,
+		Limit:     req.Limit,
+	}
+	for _, a := range req.Attendees {
+		wh, err := parseWorkingHours(a.WorkingHours)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		q.Attendees = append(q.Attendees, services.SuggestAttendee{
+			UserID:       a.UserID,
+			Optional:     a.Optional,
+			WorkingHours: wh,
+		})
+	}
+
+	suggestions, err := h.events.SuggestTimes(r.Context(), q)
+	if err != nil {
+		if writeAccessError(w, err) {
+			return
+		}
+		if isInvalidInput(err) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("suggest times failed", "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	resp := suggestResponse{
+		Suggestions: make([]suggestionResponse, 0, len(suggestions)),
+	}
+	for _, s := range suggestions {
+		resp.Suggestions = append(resp.Suggestions, suggestionResponse{
+			Start:               s.Start.Format(time.RFC3339),
+			End:                 s.End.Format(time.RFC3339),
+			Score:               s.Score,
+			Busy:                s.Busy,
+			OutsideWorkingHours: s.OutsideWorkingHours,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
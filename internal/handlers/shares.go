@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"calendar/internal/logger"
+	"calendar/internal/repos"
+)
+
+// /api/shares/{id}
+func getShareIDFromPath(r *http.Request) string {
+	path := strings.TrimPrefix(r.URL.Path, "/api/shares/")
+	path = strings.Trim(path, "/")
+	if _, err := uuid.Parse(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// writeShareError отвечает на ошибку сервиса доступов.
+func (h *Handlers) writeShareError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "share not found")
+	case writeAccessError(w, err):
+	case isInvalidInput(err):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		logger.FromContext(r.Context()).Error(msg, "err", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+	}
+}
+
+// ShareAccess — POST /api/shares
+// Выдаёт доступ или меняет роль уже выданного.
+func (h *Handlers) ShareAccess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req shareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.CalendarID != "" {
+		if _, err := uuid.Parse(req.CalendarID); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid calendar_id")
+			return
+		}
+	}
+
+	ownerID, ok := requestOwner(w, r, req.OwnerID)
+	if !ok {
+		return
+	}
+
+	sh := &repos.Share{
+		ID:         uuid.New().String(),
+		OwnerID:    ownerID,
+		CalendarID: req.CalendarID,
+		GranteeID:  req.GranteeID,
+		Role:       req.Role,
+	}
+	if err := h.events.ShareAccess(r.Context(), sh); err != nil {
+		h.writeShareError(w, r, err, "share access failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toShareResponse(*sh))
+}
+
+// ListShares — GET /api/shares?owner_id=...
+func (h *Handlers) ListShares(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ownerID, ok := requestOwner(w, r, r.URL.Query().Get("owner_id"))
+	if !ok {
+		return
+	}
+
+	shares, err := h.events.ListShares(r.Context(), ownerID)
+	if err != nil {
+		h.writeShareError(w, r, err, "list shares failed")
+		return
+	}
+
+	resp := listSharesResponse{
+		Shares: make([]shareResponse, 0, len(shares)),
+	}
+	for _, sh := range shares {
+		resp.Shares = append(resp.Shares, toShareResponse(sh))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RevokeAccess — DELETE /api/shares/{id}
+func (h *Handlers) RevokeAccess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := getShareIDFromPath(r)
+	if id == "" {
+		writeError(w, http.StatusNotFound, "share not found")
+		return
+	}
+
+	if err := h.events.RevokeAccess(r.Context(), id); err != nil {
+		h.writeShareError(w, r, err, "revoke access failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+// Package health отдаёт пробы живости и готовности сервиса для оркестратора.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"calendar/internal/config"
+	"calendar/internal/logger"
+)
+
+// Check проверяет один компонент сервиса; ошибка — компонент не готов.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker собирает проверки компонентов и отдаёт по ним пробы /livez и /readyz.
+type Checker struct {
+	log     logger.Logger
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+// NewChecker создаёт Checker; каждая проверка ограничена cfg.Health.Timeout.
+func NewChecker(cfg *config.Config, log logger.Logger) *Checker {
+	return &Checker{
+		log:     log,
+		timeout: cfg.Health.Timeout,
+	}
+}
+
+// Add добавляет проверку компонента name в пробу готовности.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// componentStatus — состояние одного компонента в ответе /readyz.
+type componentStatus struct {
+	Status     string  `json:"status"` // "ok" / "fail"
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+type readyResponse struct {
+	Status     string                     `json:"status"` // "ok", если готовы все компоненты
+	Components map[string]componentStatus `json:"components"`
+}
+
+// RegisterRoutes регистрирует пробы:
+//
+//	GET /livez  — процесс жив и обслуживает запросы; зависимости не проверяются,
+//	              чтобы оркестратор не перезапускал сервис из‑за недоступной базы
+//	GET /readyz — все компоненты готовы (200) или нет (503), по каждому — статус в JSON
+func (c *Checker) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/livez", c.handleLive)
+	mux.HandleFunc("/readyz", c.handleReady)
+}
+
+func (c *Checker) handleLive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
+
+func (c *Checker) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := c.ready(r.Context())
+
+	status := http.StatusOK
+	if resp.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// ready выполняет все проверки параллельно, каждую со своим таймаутом.
+func (c *Checker) ready(ctx context.Context) readyResponse {
+	statuses := make([]componentStatus, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = c.run(ctx, nc)
+		}()
+	}
+	wg.Wait()
+
+	resp := readyResponse{Status: "ok", Components: make(map[string]componentStatus, len(c.checks))}
+	for i, nc := range c.checks {
+		if statuses[i].Status != "ok" {
+			resp.Status = "fail"
+		}
+		resp.Components[nc.name] = statuses[i]
+	}
+	return resp
+}
+
+// run выполняет одну проверку. Если проверка не уложилась в таймаут, компонент считается
+// неготовым, даже если сама проверка ctx не соблюдает и ещё не вернулась.
+func (c *Checker) run(ctx context.Context, nc namedCheck) componentStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- nc.check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	st := componentStatus{Status: "ok", DurationMS: float64(time.Since(start).Microseconds()) / 1000}
+
+	if err != nil {
+		c.log.Warn("readiness check failed", "component", nc.name, "error", err)
+		st.Status = "fail"
+		st.Error = err.Error()
+	}
+	return st
+}
@@ -0,0 +1,292 @@
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+
+	"calendar/internal/repos"
+)
+
+// Entry — один VEVENT из импортируемого календаря.
+type Entry struct {
+	// Event заполнен, если Err == nil. ICalUID — UID события; у переопределения вхождения
+	// заполнен RecurrenceID, а серию (RecurringEventID) по UID находит вызывающий.
+	Event repos.Event
+	// Err — почему VEVENT не удалось разобрать.
+	Err error
+}
+
+// Decode читает VEVENT из одного или нескольких VCALENDAR в r. Ошибка возвращается,
+// только если поток не является iCalendar; ошибки отдельных VEVENT попадают в Entry.Err.
+//
+// Время с TZID переводится из указанного часового пояса: IANA, X-LIC-LOCATION из VTIMEZONE,
+// пояс Windows (как пишет Outlook) или постоянное смещение из VTIMEZONE без перехода на летнее
+// время. «Плавающее» время и даты (VALUE=DATE) читаются как UTC и помечаются Event.Floating —
+// в пояс календаря, куда попадёт событие, их переносит вызывающий (см. repos.Event.Floating).
+// Пояс DTSTART сохраняется в Event.TimeZone: в нём разворачивается RRULE серии.
+func Decode(r io.Reader) ([]Entry, error) {
+	dec := ical.NewDecoder(r)
+
+	var entries []Entry
+	for {
+		cal, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		zones := timezones(cal)
+		for _, ev := range cal.Events() {
+			e, err := decodeEvent(ev.Component, zones)
+			entries = append(entries, Entry{Event: e, Err: err})
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("ics: no VEVENT found")
+	}
+	return entries, nil
+}
+
+// timezones сопоставляет TZID из VTIMEZONE календаря с поясом IANA: с X-LIC-LOCATION,
+// которую добавляют Thunderbird и другие клиенты на основе libical, а если её нет —
+// с постоянным смещением пояса без перехода на летнее время (см. fixedZone).
+func timezones(cal *ical.Calendar) map[string]string {
+	zones := make(map[string]string)
+	for _, tz := range cal.Children {
+		if tz.Name != ical.CompTimezone {
+			continue
+		}
+		tzid, _ := tz.Props.Text(ical.PropTimezoneID)
+		if tzid == "" {
+			continue
+		}
+		if location, _ := tz.Props.Text("X-LIC-LOCATION"); location != "" {
+			zones[tzid] = location
+		} else if location, ok := fixedZone(tz); ok {
+			zones[tzid] = location
+		}
+	}
+	return zones
+}
+
+// fixedZone возвращает пояс Etc/GMT±N для VTIMEZONE, все наблюдения которого — один STANDARD
+// с целым числом часов смещения. Пояса с летним временем так не описать: правила перехода
+// нужны базе IANA, а не только смещения.
+func fixedZone(tz *ical.Component) (string, bool) {
+	if len(tz.Children) != 1 || tz.Children[0].Name != ical.CompTimezoneStandard {
+		return "", false
+	}
+	offset := tz.Children[0].Props.Get(ical.PropTimezoneOffsetTo)
+	if offset == nil {
+		return "", false
+	}
+	t, err := time.Parse("-0700", offset.Value)
+	if err != nil {
+		return "", false
+	}
+	_, seconds := t.Zone()
+	if seconds%3600 != 0 {
+		return "", false
+	}
+
+	// Знак у Etc/GMT±N обратный: Etc/GMT-3 — это UTC+3.
+	switch hours := seconds / 3600; {
+	case hours == 0:
+		return "Etc/UTC", true
+	case hours > 0:
+		return fmt.Sprintf("Etc/GMT-%d", hours), true
+	default:
+		return fmt.Sprintf("Etc/GMT+%d", -hours), true
+	}
+}
+
+func decodeEvent(ev *ical.Component, zones map[string]string) (repos.Event, error) {
+	var e repos.Event
+
+	uid, err := ev.Props.Text(ical.PropUID)
+	if err != nil || uid == "" {
+		return e, errors.New("missing UID")
+	}
+	e.ICalUID = uid
+
+	dtstart := ev.Props.Get(ical.PropDateTimeStart)
+	start, allDay, floating, err := propTime(dtstart, zones)
+	if err != nil {
+		return e, fmt.Errorf("DTSTART: %w", err)
+	}
+	e.StartTime = start
+	e.Floating = floating
+	if dtstart.Params.Get(ical.PropTimezoneID) != "" {
+		// propTime вернул время в поясе TZID, уже приведённом к названию IANA.
+		e.TimeZone = start.Location().String()
+	}
+
+	// Остальные моменты события должны быть того же вида, что DTSTART (RFC 5545, 3.8.2.2,
+	// 3.8.4.4): «плавающее» время переносится в пояс календаря целиком, вместе с ними.
+	eventTime := func(p *ical.Prop) (time.Time, error) {
+		t, _, f, err := propTime(p, zones)
+		if err == nil && f != floating {
+			err = errMixedTimes
+		}
+		return t, err
+	}
+
+	switch {
+	case ev.Props.Get(ical.PropDateTimeEnd) != nil:
+		e.EndTime, err = eventTime(ev.Props.Get(ical.PropDateTimeEnd))
+		if err != nil {
+			return e, fmt.Errorf("DTEND: %w", err)
+		}
+	case ev.Props.Get(ical.PropDuration) != nil:
+		d, err := ev.Props.Get(ical.PropDuration).Duration()
+		if err != nil {
+			return e, fmt.Errorf("DURATION: %w", err)
+		}
+		e.EndTime = start.Add(d)
+	case allDay:
+		// RFC 5545, 3.6.1: событие на дату без DTEND длится один день.
+		e.EndTime = start.AddDate(0, 0, 1)
+	default:
+		e.EndTime = start
+	}
+	if e.EndTime.Before(e.StartTime) {
+		return e, errors.New("DTEND is before DTSTART")
+	}
+
+	if e.Title, err = ev.Props.Text(ical.PropSummary); err != nil {
+		return e, fmt.Errorf("SUMMARY: %w", err)
+	}
+	if e.Title == "" {
+		e.Title = "(no title)"
+	}
+	if e.Description, err = ev.Props.Text(ical.PropDescription); err != nil {
+		return e, fmt.Errorf("DESCRIPTION: %w", err)
+	}
+
+	if p := ev.Props.Get(ical.PropRecurrenceID); p != nil {
+		if e.RecurrenceID, err = eventTime(p); err != nil {
+			return e, fmt.Errorf("RECURRENCE-ID: %w", err)
+		}
+	}
+	if p := ev.Props.Get(ical.PropRecurrenceRule); p != nil {
+		e.RRule = p.Value
+	}
+	if e.ExDates, err = propTimes(ev.Props.Values(ical.PropExceptionDates), eventTime); err != nil {
+		return e, fmt.Errorf("EXDATE: %w", err)
+	}
+	if e.RDates, err = propTimes(ev.Props.Values(ical.PropRecurrenceDates), eventTime); err != nil {
+		return e, fmt.Errorf("RDATE: %w", err)
+	}
+
+	e.Reminders = reminders(ev)
+	return e, nil
+}
+
+// errMixedTimes — у события «плавающее» время смешано со временем в поясе или в UTC.
+var errMixedTimes = errors.New("local (floating) time mixed with time in a zone")
+
+// propTime разбирает DATE-TIME или DATE (allDay) значение свойства. floating — время без пояса
+// (ни TZID, ни UTC) или дата; оно возвращается в UTC с тем же местным временем.
+func propTime(p *ical.Prop, zones map[string]string) (t time.Time, allDay, floating bool, err error) {
+	if p == nil {
+		return time.Time{}, false, false, errors.New("missing")
+	}
+
+	loc := time.UTC
+	tzid := p.Params.Get(ical.PropTimezoneID)
+	if tzid != "" {
+		if loc, err = location(tzid, zones); err != nil {
+			return time.Time{}, false, false, err
+		}
+	}
+
+	// TZID уже учтён в loc: go-ical умеет только названия из базы IANA.
+	prop := *p
+	prop.Params = make(ical.Params, len(p.Params))
+	for name, values := range p.Params {
+		if name != ical.PropTimezoneID {
+			prop.Params[name] = values
+		}
+	}
+
+	t, err = prop.DateTime(loc)
+	if err != nil {
+		return time.Time{}, false, false, err
+	}
+	allDay = prop.ValueType() == ical.ValueDate || len(prop.Value) == len("20060102")
+	floating = allDay || tzid == "" && !strings.HasSuffix(prop.Value, "Z")
+	return t, allDay, floating, nil
+}
+
+// propTimes разбирает списки моментов EXDATE/RDATE: свойств может быть несколько,
+// и каждое может содержать несколько значений через запятую.
+func propTimes(props []ical.Prop, propTime func(*ical.Prop) (time.Time, error)) ([]time.Time, error) {
+	var times []time.Time
+	for _, p := range props {
+		if p.ValueType() == ical.ValuePeriod {
+			return nil, errors.New("periods are not supported")
+		}
+		for _, v := range strings.Split(p.Value, ",") {
+			value := p
+			value.Value = v
+			t, err := propTime(&value)
+			if err != nil {
+				return nil, err
+			}
+			times = append(times, t)
+		}
+	}
+	return times, nil
+}
+
+func location(tzid string, zones map[string]string) (*time.Location, error) {
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return loc, nil
+	}
+	for _, names := range []map[string]string{zones, windowsZones} {
+		if name, ok := names[tzid]; ok {
+			if loc, err := time.LoadLocation(name); err == nil {
+				return loc, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown TZID %q", tzid)
+}
+
+// reminders переводит VALARM с относительным TRIGGER до начала события в минуты.
+// Напоминания после начала, относительно конца и на абсолютное время пропускаются.
+func reminders(ev *ical.Component) []int {
+	seen := make(map[int]struct{})
+	var res []int
+	for _, alarm := range ev.Children {
+		if alarm.Name != ical.CompAlarm {
+			continue
+		}
+		trigger := alarm.Props.Get(ical.PropTrigger)
+		if trigger == nil || trigger.Params.Get("RELATED") == "END" {
+			continue
+		}
+		d, err := trigger.Duration()
+		if err != nil || d > 0 {
+			continue
+		}
+
+		minutes := int(-d / time.Minute)
+		if _, ok := seen[minutes]; ok {
+			continue
+		}
+		seen[minutes] = struct{}{}
+		res = append(res, minutes)
+	}
+	sort.Ints(res)
+	return res
+}
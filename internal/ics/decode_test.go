@@ -0,0 +1,210 @@
+package ics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendar оборачивает строки VEVENT (и VTIMEZONE) в VCALENDAR с переводами строк CRLF.
+func calendar(lines ...string) string {
+	all := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//test//EN"}, lines...)
+	all = append(all, "END:VCALENDAR")
+	return strings.Join(all, "\r\n") + "\r\n"
+}
+
+func TestDecodeTimeZones(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plus3, err := time.LoadLocation("Etc/GMT-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		input        string
+		wantStart    time.Time
+		wantEnd      time.Time
+		wantZone     string
+		wantEx       []time.Time
+		wantFloating bool
+		wantErr      bool
+	}{
+		{
+			name: "IANA TZID",
+			input: calendar(
+				"BEGIN:VEVENT", "UID:a", "SUMMARY:Standup",
+				"DTSTART;TZID=Europe/Berlin:20260323T003000",
+				"DTEND;TZID=Europe/Berlin:20260323T010000",
+				"RRULE:FREQ=WEEKLY",
+				"EXDATE;TZID=Europe/Berlin:20260330T003000",
+				"END:VEVENT",
+			),
+			wantStart: time.Date(2026, time.March, 23, 0, 30, 0, 0, berlin),
+			wantEnd:   time.Date(2026, time.March, 23, 1, 0, 0, 0, berlin),
+			wantZone:  "Europe/Berlin",
+			wantEx:    []time.Time{time.Date(2026, time.March, 30, 0, 30, 0, 0, berlin)},
+		},
+		{
+			name: "custom TZID resolved through VTIMEZONE",
+			input: calendar(
+				"BEGIN:VTIMEZONE", "TZID:/mozilla.org/20050126_1/Europe/Berlin", "X-LIC-LOCATION:Europe/Berlin",
+				"BEGIN:STANDARD", "TZOFFSETFROM:+0200", "TZOFFSETTO:+0100", "DTSTART:19701025T030000", "END:STANDARD",
+				"END:VTIMEZONE",
+				"BEGIN:VEVENT", "UID:b", "SUMMARY:Review",
+				`DTSTART;TZID="/mozilla.org/20050126_1/Europe/Berlin":20260701T090000`,
+				`DTEND;TZID="/mozilla.org/20050126_1/Europe/Berlin":20260701T100000`,
+				"END:VEVENT",
+			),
+			wantStart: time.Date(2026, time.July, 1, 9, 0, 0, 0, berlin),
+			wantEnd:   time.Date(2026, time.July, 1, 10, 0, 0, 0, berlin),
+			wantZone:  "Europe/Berlin",
+		},
+		{
+			name: "Windows TZID",
+			input: calendar(
+				"BEGIN:VEVENT", "UID:w", "SUMMARY:Planning",
+				"DTSTART;TZID=Russian Standard Time:20260701T090000",
+				"DTEND;TZID=Russian Standard Time:20260701T100000",
+				"END:VEVENT",
+			),
+			wantStart: time.Date(2026, time.July, 1, 9, 0, 0, 0, moscow),
+			wantEnd:   time.Date(2026, time.July, 1, 10, 0, 0, 0, moscow),
+			wantZone:  "Europe/Moscow",
+		},
+		{
+			name: "custom TZID with a fixed offset in VTIMEZONE",
+			input: calendar(
+				"BEGIN:VTIMEZONE", "TZID:Custom Time",
+				"BEGIN:STANDARD", "TZOFFSETFROM:+0300", "TZOFFSETTO:+0300", "DTSTART:16010101T000000", "END:STANDARD",
+				"END:VTIMEZONE",
+				"BEGIN:VEVENT", "UID:x", "SUMMARY:Retro",
+				`DTSTART;TZID="Custom Time":20260701T090000`,
+				`DTEND;TZID="Custom Time":20260701T100000`,
+				"END:VEVENT",
+			),
+			wantStart: time.Date(2026, time.July, 1, 9, 0, 0, 0, plus3),
+			wantEnd:   time.Date(2026, time.July, 1, 10, 0, 0, 0, plus3),
+			wantZone:  "Etc/GMT-3",
+		},
+		{
+			name: "UTC time keeps the calendar zone",
+			input: calendar(
+				"BEGIN:VEVENT", "UID:c", "SUMMARY:Sync",
+				"DTSTART:20260701T090000Z", "DURATION:PT45M",
+				"END:VEVENT",
+			),
+			wantStart: time.Date(2026, time.July, 1, 9, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, time.July, 1, 9, 45, 0, 0, time.UTC),
+		},
+		{
+			name: "floating time is marked",
+			input: calendar(
+				"BEGIN:VEVENT", "UID:d", "SUMMARY:Lunch",
+				"DTSTART:20260701T120000", "DTEND:20260701T130000",
+				"END:VEVENT",
+			),
+			wantStart:    time.Date(2026, time.July, 1, 12, 0, 0, 0, time.UTC),
+			wantEnd:      time.Date(2026, time.July, 1, 13, 0, 0, 0, time.UTC),
+			wantFloating: true,
+		},
+		{
+			name: "all-day event without DTEND",
+			input: calendar(
+				"BEGIN:VEVENT", "UID:e", "SUMMARY:Holiday",
+				"DTSTART;VALUE=DATE:20260501",
+				"END:VEVENT",
+			),
+			wantStart:    time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:      time.Date(2026, time.May, 2, 0, 0, 0, 0, time.UTC),
+			wantFloating: true,
+		},
+		{
+			name: "floating time mixed with UTC",
+			input: calendar(
+				"BEGIN:VEVENT", "UID:m", "SUMMARY:Lunch",
+				"DTSTART:20260701T120000", "DTEND:20260701T130000Z",
+				"END:VEVENT",
+			),
+			wantErr: true,
+		},
+		{
+			name: "unknown TZID",
+			input: calendar(
+				"BEGIN:VEVENT", "UID:f", "SUMMARY:Lost",
+				"DTSTART;TZID=Middle Earth:20260701T090000",
+				"END:VEVENT",
+			),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Decode(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e, err := entries[0].Event, entries[0].Err
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Decode = %+v, want an error", e)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("entry error: %v", err)
+			}
+
+			if !e.StartTime.Equal(tt.wantStart) || !e.EndTime.Equal(tt.wantEnd) {
+				t.Errorf("time = %v–%v, want %v–%v", e.StartTime, e.EndTime, tt.wantStart, tt.wantEnd)
+			}
+			if e.TimeZone != tt.wantZone {
+				t.Errorf("TimeZone = %q, want %q", e.TimeZone, tt.wantZone)
+			}
+			if e.Floating != tt.wantFloating {
+				t.Errorf("Floating = %v, want %v", e.Floating, tt.wantFloating)
+			}
+			if !equalTimes(e.ExDates, tt.wantEx) {
+				t.Errorf("ExDates = %v, want %v", e.ExDates, tt.wantEx)
+			}
+		})
+	}
+}
+
+func TestDecodeNotCalendar(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"not iCalendar", "hello, world\r\n"},
+		{"no events", calendar()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if entries, err := Decode(strings.NewReader(tt.input)); err == nil {
+				t.Errorf("Decode = %+v, want an error", entries)
+			}
+		})
+	}
+}
+
+func TestWindowsZonesAreKnown(t *testing.T) {
+	for windows, iana := range windowsZones {
+		if _, err := time.LoadLocation(iana); err != nil {
+			t.Errorf("%q maps to %q: %v", windows, iana, err)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Package ics переводит события календаря в формат iCalendar (RFC 5545) и обратно.
+package ics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/emersion/go-ical"
+
+	"calendar/internal/repos"
+)
+
+// ProdID — идентификатор продукта в PRODID выгружаемых календарей.
+const ProdID = "-//glebershov//calendar//EN"
+
+// Сколько клиентам (Outlook, Apple Calendar, Thunderbird) ждать до следующей загрузки подписки.
+const refreshInterval = time.Hour
+
+// maxLineOctets — предельная длина строки без CRLF (RFC 5545, 3.1).
+const maxLineOctets = 75
+
+// NewCalendar собирает VCALENDAR с названием name из событий events.
+// Серии выгружаются с RRULE/EXDATE/RDATE, переопределения вхождений — отдельными
+// VEVENT с UID серии и RECURRENCE-ID.
+func NewCalendar(name string, events []repos.Event) *ical.Calendar {
+	cal := NewObject(events)
+	cal.Props.SetText(ical.PropMethod, "PUBLISH")
+	cal.Props.SetText(ical.PropName, name)
+
+	// Нестандартные аналоги NAME и REFRESH-INTERVAL, которые понимают Outlook и Apple Calendar.
+	// Тип значения у X-свойств не указывается: go-ical добавил бы VALUE=.
+	calName := ical.NewProp("X-WR-CALNAME")
+	calName.SetText(name)
+	calName.Params.Del(ical.ParamValue)
+	cal.Props.Set(calName)
+	ttl := ical.NewProp("X-PUBLISHED-TTL")
+	ttl.SetDuration(refreshInterval)
+	ttl.Params.Del(ical.ParamValue)
+	cal.Props.Set(ttl)
+
+	// RFC 7986 требует у REFRESH-INTERVAL явный VALUE=DURATION.
+	refresh := ical.NewProp(ical.PropRefreshInterval)
+	refresh.SetDuration(refreshInterval)
+	refresh.Params.Set(ical.ParamValue, string(ical.ValueDuration))
+	cal.Props.Set(refresh)
+	return cal
+}
+
+// NewObject собирает VCALENDAR без METHOD и свойств подписки — в таком виде
+// события хранятся в коллекциях CalDAV (RFC 4791, 4.1). Для каждого часового пояса,
+// на который ссылаются TZID событий, добавляется VTIMEZONE.
+func NewObject(events []repos.Event) *ical.Calendar {
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropProductID, ProdID)
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropCalendarScale, "GREGORIAN")
+
+	// Правила VTIMEZONE выводятся из переходов года самого раннего события в поясе.
+	years := make(map[string]int)
+	var zones []*time.Location
+	for _, e := range events {
+		loc := eventZone(e)
+		if loc == time.UTC {
+			continue
+		}
+		year := e.StartTime.In(loc).Year()
+		if first, ok := years[loc.String()]; !ok {
+			years[loc.String()] = year
+			zones = append(zones, loc)
+		} else if year < first {
+			years[loc.String()] = year
+		}
+	}
+	for _, loc := range zones {
+		cal.Children = append(cal.Children, newTimezone(loc, years[loc.String()]))
+	}
+
+	for _, e := range events {
+		cal.Children = append(cal.Children, newEvent(e))
+	}
+	return cal
+}
+
+// UID возвращает iCalendar UID события: сохранённый при импорте или ID
+// (у вхождения серии — ID серии, чтобы серия и её вхождения были одним объектом).
+func UID(e repos.Event) string {
+	if e.ICalUID != "" {
+		return e.ICalUID
+	}
+	if e.RecurringEventID != "" {
+		return e.RecurringEventID
+	}
+	return e.ID
+}
+
+// newEvent переводит событие в VEVENT. Время события, его вхождений и исключений выгружается
+// с TZID пояса события (DTSTART;TZID=Europe/Berlin:...), чтобы клиент разворачивал RRULE
+// в том же поясе, что и сервис; служебные метки времени — в UTC.
+func newEvent(e repos.Event) *ical.Component {
+	ev := ical.NewEvent()
+	loc := eventZone(e)
+
+	if e.RecurringEventID != "" {
+		ev.Props.SetDateTime(ical.PropRecurrenceID, e.RecurrenceID.In(loc))
+	}
+	ev.Props.SetText(ical.PropUID, UID(e))
+	ev.Props.SetDateTime(ical.PropDateTimeStamp, e.UpdatedAt.UTC())
+	ev.Props.SetDateTime(ical.PropCreated, e.CreatedAt.UTC())
+	ev.Props.SetDateTime(ical.PropLastModified, e.UpdatedAt.UTC())
+	ev.Props.SetDateTime(ical.PropDateTimeStart, e.StartTime.In(loc))
+	ev.Props.SetDateTime(ical.PropDateTimeEnd, e.EndTime.In(loc))
+	ev.Props.SetText(ical.PropSummary, text(e.Title))
+	if e.Description != "" {
+		ev.Props.SetText(ical.PropDescription, text(e.Description))
+	}
+
+	if e.RRule != "" {
+		rule := ical.NewProp(ical.PropRecurrenceRule)
+		rule.Value = e.RRule
+		ev.Props.Set(rule)
+	}
+	for _, t := range e.ExDates {
+		p := ical.NewProp(ical.PropExceptionDates)
+		p.SetDateTime(t.In(loc))
+		ev.Props.Add(p)
+	}
+	for _, t := range e.RDates {
+		p := ical.NewProp(ical.PropRecurrenceDates)
+		p.SetDateTime(t.In(loc))
+		ev.Props.Add(p)
+	}
+
+	for _, minutes := range e.Reminders {
+		ev.Children = append(ev.Children, newAlarm(e.Title, minutes))
+	}
+	return ev.Component
+}
+
+// newAlarm — VALARM за minutes минут до начала события.
+func newAlarm(title string, minutes int) *ical.Component {
+	alarm := ical.NewComponent(ical.CompAlarm)
+	alarm.Props.SetText(ical.PropAction, "DISPLAY")
+	alarm.Props.SetText(ical.PropDescription, text(title))
+
+	trigger := ical.NewProp(ical.PropTrigger)
+	trigger.SetDuration(-time.Duration(minutes) * time.Minute)
+	alarm.Props.Set(trigger)
+	return alarm
+}
+
+// text приводит переводы строк к LF: go-ical экранирует только их, а голый CR в значении недопустим.
+func text(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
+
+// Encode записывает календарь в w. В отличие от ical.Encoder переносит строки длиннее
+// 75 октетов и допускает календарь без событий: пустая подписка — нормальная ситуация.
+func Encode(w io.Writer, cal *ical.Calendar) error {
+	var buf bytes.Buffer
+	if err := encodeComponent(&buf, cal.Component); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func encodeComponent(buf *bytes.Buffer, comp *ical.Component) error {
+	if err := encodeProp(buf, &ical.Prop{Name: "BEGIN", Value: comp.Name}); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(comp.Props))
+	for name := range comp.Props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, prop := range comp.Props[name] {
+			if err := encodeProp(buf, &prop); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, child := range comp.Children {
+		if err := encodeComponent(buf, child); err != nil {
+			return err
+		}
+	}
+	return encodeProp(buf, &ical.Prop{Name: "END", Value: comp.Name})
+}
+
+// encodeProp записывает свойство одной логической строкой (RFC 5545, 3.1–3.2).
+func encodeProp(buf *bytes.Buffer, prop *ical.Prop) error {
+	var line strings.Builder
+	line.WriteString(prop.Name)
+
+	params := make([]string, 0, len(prop.Params))
+	for name := range prop.Params {
+		params = append(params, name)
+	}
+	sort.Strings(params)
+	for _, name := range params {
+		line.WriteString(";" + name + "=")
+		for i, v := range prop.Params[name] {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			if strings.ContainsAny(v, "\"\r\n") {
+				return fmt.Errorf("ics: invalid value of parameter %s of %s", name, prop.Name)
+			}
+			if strings.ContainsAny(v, ";:,") {
+				v = `"` + v + `"`
+			}
+			line.WriteString(v)
+		}
+	}
+
+	if strings.ContainsAny(prop.Value, "\r\n") {
+		return fmt.Errorf("ics: value of %s contains a line break", prop.Name)
+	}
+	line.WriteString(":" + prop.Value)
+
+	fold(buf, line.String())
+	return nil
+}
+
+// fold записывает line, перенося её по 75 октетов (продолжение начинается с пробела)
+// и не разрывая многобайтные символы UTF-8.
+func fold(buf *bytes.Buffer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// Пробел в начале строки-продолжения тоже занимает октет.
+		limit = maxLineOctets - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
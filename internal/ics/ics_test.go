@@ -0,0 +1,212 @@
+package ics
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
+
+	"github.com/emersion/go-ical"
+
+	"calendar/internal/repos"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		lines int
+	}{
+		{"short", "SUMMARY:Standup", 1},
+		{"exactly the limit", "SUMMARY:" + strings.Repeat("a", maxLineOctets-len("SUMMARY:")), 1},
+		{"one octet over", "SUMMARY:" + strings.Repeat("a", maxLineOctets-len("SUMMARY:")+1), 2},
+		{"long ascii", "DESCRIPTION:" + strings.Repeat("x", 300), 5},
+		{"cyrillic", "SUMMARY:" + strings.Repeat("Планёрка ", 20), 5},
+		{"emoji", "SUMMARY:" + strings.Repeat("🎉", 40), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			fold(&buf, tt.line)
+			out := buf.String()
+
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("output %q does not end with CRLF", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if len(lines) != tt.lines {
+				t.Errorf("got %d lines, want %d", len(lines), tt.lines)
+			}
+
+			var unfolded strings.Builder
+			for i, l := range lines {
+				if len(l) > maxLineOctets {
+					t.Errorf("line %d is %d octets long", i, len(l))
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d splits a UTF-8 sequence: %q", i, l)
+				}
+				if i > 0 {
+					if !strings.HasPrefix(l, " ") {
+						t.Fatalf("continuation line %d does not start with a space: %q", i, l)
+					}
+					l = l[1:]
+				}
+				unfolded.WriteString(l)
+			}
+			if unfolded.String() != tt.line {
+				t.Errorf("unfolded line = %q, want %q", unfolded.String(), tt.line)
+			}
+		})
+	}
+}
+
+func TestObjectRoundTrip(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, time.March, 23, 0, 30, 0, 0, berlin)
+
+	tests := []struct {
+		name  string
+		event repos.Event
+	}{
+		{
+			name: "series in a local zone",
+			event: repos.Event{
+				ID:        "d3b1c1d6-2f0e-4a53-9bb8-5a2f2b3e7c10",
+				Title:     "Планёрка",
+				StartTime: start,
+				EndTime:   start.Add(30 * time.Minute),
+				RRule:     "FREQ=WEEKLY;BYDAY=MO",
+				ExDates:   []time.Time{start.AddDate(0, 0, 7)},
+				RDates:    []time.Time{start.AddDate(0, 0, 2)},
+				Reminders: []int{10},
+				TimeZone:  "Europe/Berlin",
+			},
+		},
+		{
+			name: "utc event",
+			event: repos.Event{
+				ID:          "8f2d8c8a-7b8e-4d1f-a0d5-0e8f6a1b2c3d",
+				Title:       "Release",
+				Description: "line one\nline two",
+				StartTime:   time.Date(2026, time.April, 1, 12, 0, 0, 0, time.UTC),
+				EndTime:     time.Date(2026, time.April, 1, 13, 0, 0, 0, time.UTC),
+				TimeZone:    "UTC",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, NewObject([]repos.Event{tt.event})); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			entries, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if len(entries) != 1 || entries[0].Err != nil {
+				t.Fatalf("Decode = %+v", entries)
+			}
+
+			want, got := tt.event, entries[0].Event
+			if want.TimeZone == "UTC" {
+				// Время в UTC выгружается с Z и без TZID.
+				want.TimeZone = ""
+			}
+			if got.ICalUID != want.ID || got.Title != want.Title || got.Description != want.Description ||
+				got.RRule != want.RRule || got.TimeZone != want.TimeZone {
+				t.Errorf("Decode = %+v, want %+v", got, want)
+			}
+			if !got.StartTime.Equal(want.StartTime) || !got.EndTime.Equal(want.EndTime) {
+				t.Errorf("time = %v–%v, want %v–%v", got.StartTime, got.EndTime, want.StartTime, want.EndTime)
+			}
+			if !equalTimes(got.ExDates, want.ExDates) || !equalTimes(got.RDates, want.RDates) {
+				t.Errorf("exdates %v, rdates %v; want %v, %v", got.ExDates, got.RDates, want.ExDates, want.RDates)
+			}
+			if len(got.Reminders) != len(want.Reminders) {
+				t.Errorf("reminders = %v, want %v", got.Reminders, want.Reminders)
+			}
+		})
+	}
+}
+
+func TestNewTimezone(t *testing.T) {
+	tests := []struct {
+		zone string
+		// Наблюдения по порядку переходов года: тип, TZOFFSETFROM, TZOFFSETTO, RRULE.
+		want [][4]string
+	}{
+		{
+			zone: "Europe/Berlin",
+			want: [][4]string{
+				{ical.CompTimezoneDaylight, "+0100", "+0200", "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU"},
+				{ical.CompTimezoneStandard, "+0200", "+0100", "FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU"},
+			},
+		},
+		{
+			zone: "America/New_York",
+			want: [][4]string{
+				{ical.CompTimezoneDaylight, "-0500", "-0400", "FREQ=YEARLY;BYMONTH=3;BYDAY=2SU"},
+				{ical.CompTimezoneStandard, "-0400", "-0500", "FREQ=YEARLY;BYMONTH=11;BYDAY=1SU"},
+			},
+		},
+		{
+			zone: "Australia/Sydney",
+			want: [][4]string{
+				{ical.CompTimezoneStandard, "+1100", "+1000", "FREQ=YEARLY;BYMONTH=4;BYDAY=1SU"},
+				{ical.CompTimezoneDaylight, "+1000", "+1100", "FREQ=YEARLY;BYMONTH=10;BYDAY=1SU"},
+			},
+		},
+		{
+			zone: "Asia/Kolkata",
+			want: [][4]string{
+				{ical.CompTimezoneStandard, "+0530", "+0530", ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tz := newTimezone(loc, 2026)
+
+			if id, _ := tz.Props.Text(ical.PropTimezoneID); id != tt.zone {
+				t.Errorf("TZID = %q, want %q", id, tt.zone)
+			}
+			if len(tz.Children) != len(tt.want) {
+				t.Fatalf("got %d observances, want %d", len(tz.Children), len(tt.want))
+			}
+			for i, obs := range tz.Children {
+				var rule string
+				if p := obs.Props.Get(ical.PropRecurrenceRule); p != nil {
+					rule = p.Value
+				}
+				got := [4]string{
+					obs.Name,
+					obs.Props.Get(ical.PropTimezoneOffsetFrom).Value,
+					obs.Props.Get(ical.PropTimezoneOffsetTo).Value,
+					rule,
+				}
+				if got != tt.want[i] {
+					t.Errorf("observance %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func equalTimes(a, b []time.Time) bool {
+	return slices.EqualFunc(a, b, time.Time.Equal)
+}
@@ -0,0 +1,123 @@
+package ics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/emersion/go-ical"
+
+	"calendar/internal/repos"
+)
+
+// localFormat — «плавающее» местное время DTSTART в STANDARD/DAYLIGHT.
+const localFormat = "20060102T150405"
+
+// eventZone возвращает часовой пояс, в котором выгружается время события: пояс серии
+// или UTC, если пояс не задан или неизвестен.
+func eventZone(e repos.Event) *time.Location {
+	loc, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// transition — смена смещения часового пояса в момент at.
+type transition struct {
+	at       time.Time
+	from, to int // смещения от UTC в секундах до и после смены
+}
+
+// transitions находит смены смещения пояса loc за год year. База часовых поясов Go не отдаёт
+// переходы напрямую, поэтому они ищутся по дням, а затем с точностью до минуты делением пополам.
+func transitions(loc *time.Location, year int) []transition {
+	var res []transition
+	day := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := day.AddDate(1, 0, 0)
+	for ; day.Before(end); day = day.AddDate(0, 0, 1) {
+		_, from := day.In(loc).Zone()
+		_, to := day.AddDate(0, 0, 1).In(loc).Zone()
+		if from == to {
+			continue
+		}
+
+		lo, hi := day, day.AddDate(0, 0, 1)
+		for hi.Sub(lo) > time.Minute {
+			mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Minute)
+			if _, off := mid.In(loc).Zone(); off == from {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		res = append(res, transition{at: hi, from: from, to: to})
+	}
+	return res
+}
+
+// newTimezone собирает VTIMEZONE пояса loc (RFC 5545, 3.6.5). Правила перехода на летнее
+// время и обратно выводятся из переходов года year: «n-й (или последний) день недели месяца»
+// в местное время перехода. Пояс без переходов описывается одним STANDARD.
+func newTimezone(loc *time.Location, year int) *ical.Component {
+	tz := ical.NewComponent(ical.CompTimezone)
+	tz.Props.SetText(ical.PropTimezoneID, loc.String())
+	// Тип значения у X-свойства не указывается: go-ical добавил бы VALUE=.
+	lic := ical.NewProp("X-LIC-LOCATION")
+	lic.Value = loc.String()
+	tz.Props.Set(lic)
+
+	changes := transitions(loc, year)
+	if len(changes) == 0 {
+		at := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		name, offset := at.Zone()
+		tz.Children = append(tz.Children, newObservance(ical.CompTimezoneStandard, name, offset, offset, at.Format(localFormat), ""))
+		return tz
+	}
+
+	for _, c := range changes {
+		after := c.at.In(loc)
+		kind := ical.CompTimezoneStandard
+		if after.IsDST() {
+			kind = ical.CompTimezoneDaylight
+		}
+		name, _ := after.Zone()
+		// DTSTART наблюдения — местное время перед переходом, то есть со смещением TZOFFSETFROM.
+		local := c.at.Add(time.Duration(c.from) * time.Second).UTC()
+		rule := fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%s", local.Month(), weekdayInMonth(local))
+		tz.Children = append(tz.Children, newObservance(kind, name, c.from, c.to, local.Format(localFormat), rule))
+	}
+	return tz
+}
+
+func newObservance(kind, name string, from, to int, start, rule string) *ical.Component {
+	obs := ical.NewComponent(kind)
+	obs.Props.SetText(ical.PropTimezoneName, name)
+	obs.Props.Set(&ical.Prop{Name: ical.PropTimezoneOffsetFrom, Params: ical.Params{}, Value: utcOffset(from)})
+	obs.Props.Set(&ical.Prop{Name: ical.PropTimezoneOffsetTo, Params: ical.Params{}, Value: utcOffset(to)})
+	obs.Props.Set(&ical.Prop{Name: ical.PropDateTimeStart, Params: ical.Params{}, Value: start})
+	if rule != "" {
+		obs.Props.Set(&ical.Prop{Name: ical.PropRecurrenceRule, Params: ical.Params{}, Value: rule})
+	}
+	return obs
+}
+
+// weekdayInMonth записывает день t как BYDAY: "2SU" — второе воскресенье месяца,
+// "-1SU" — последнее.
+func weekdayInMonth(t time.Time) string {
+	day := [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}[t.Weekday()]
+	daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if t.Day()+7 > daysInMonth {
+		return "-1" + day
+	}
+	return fmt.Sprintf("%d%s", (t.Day()-1)/7+1, day)
+}
+
+// utcOffset записывает смещение в секундах как UTC-OFFSET (RFC 5545, 3.3.14): "+0130".
+func utcOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds/60%60)
+}
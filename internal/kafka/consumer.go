@@ -10,15 +10,17 @@ import (
 	"calendar/internal/logger"
 
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Consumer читает сообщения из Kafka и логирует их.
 type Consumer struct {
-	reader  *kafka.Reader
-	log     logger.Logger
-	cfg     *config.Config
-	running bool
-	stopCh  chan struct{}
+	reader *kafka.Reader
+	log    logger.Logger
+	cfg    *config.Config
+	state  workerState
+	stopCh chan struct{}
 }
 
 // NewConsumer создаёт новый consumer.
@@ -43,11 +45,11 @@ func NewConsumer(cfg *config.Config, log logger.Logger) *Consumer {
 
 // Start запускает consumer, который читает сообщения из Kafka и логирует их.
 func (c *Consumer) Start(ctx context.Context) error {
-	if c.running {
+	if c.state.isRunning() {
 		return fmt.Errorf("consumer is already running")
 	}
 
-	c.running = true
+	c.state.setRunning(true)
 	c.log.Info("starting kafka consumer", "topic", c.cfg.Kafka.Topic, "group_id", "calendar-consumer-group")
 
 	// Запускаем горутину для чтения сообщений
@@ -76,53 +78,78 @@ func (c *Consumer) run(ctx context.Context) {
 				if err == context.DeadlineExceeded || err == context.Canceled {
 					continue
 				}
+				c.state.report(err)
 				c.log.Error("failed to read message from kafka", "error", err)
 				time.Sleep(1 * time.Second) // Небольшая задержка перед повтором
 				continue
 			}
 
+			c.state.report(nil)
+			consumerLag.WithLabelValues(msg.Topic, partitionLabel(msg.Partition)).Set(float64(msg.HighWaterMark - msg.Offset - 1))
+
 			// Обрабатываем сообщение
-			if err := c.processMessage(msg); err != nil {
+			if err := c.processMessage(ctx, msg); err != nil {
+				consumedMessages.WithLabelValues(msg.Topic, "failed").Inc()
 				c.log.Error("failed to process message", "error", err, "offset", msg.Offset)
+				continue
 			}
+			consumedMessages.WithLabelValues(msg.Topic, "processed").Inc()
 		}
 	}
 }
 
-// processMessage обрабатывает одно сообщение из Kafka.
-func (c *Consumer) processMessage(msg kafka.Message) error {
-	var eventMsg EventMessage
-	if err := json.Unmarshal(msg.Value, &eventMsg); err != nil {
+// processMessage обрабатывает одно сообщение из Kafka в спане, продолжающем трассу producer'а.
+func (c *Consumer) processMessage(ctx context.Context, msg kafka.Message) (err error) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier{&msg.Headers})
+	_, span := startSpan(ctx, "process", trace.SpanKindConsumer, msg.Topic, msg.Key)
+	defer func() { endSpan(span, err) }()
+
+	var change ChangeMessage
+	if err := json.Unmarshal(msg.Value, &change); err != nil {
 		return fmt.Errorf("failed to unmarshal message: %w", err)
 	}
 
 	// Устанавливаем дату отправки (если её еще нет)
-	if eventMsg.SentAt.IsZero() {
-		eventMsg.SentAt = time.Now()
+	if change.SentAt.IsZero() {
+		change.SentAt = time.Now()
+	}
+
+	// Для удаления актуален последний известный снимок, для остальных — новый.
+	snapshot := change.After
+	if snapshot == nil {
+		snapshot = change.Before
+	}
+	if snapshot == nil {
+		return fmt.Errorf("change %s of event %s has no snapshot", change.Type, change.EventID)
 	}
 
 	// Логируем сообщение
-	c.log.Info("received event from kafka",
-		"event_id", eventMsg.ID,
-		"title", eventMsg.Title,
-		"description", eventMsg.Description,
-		"start_time", eventMsg.StartTime,
-		"end_time", eventMsg.EndTime,
-		"owner_id", eventMsg.OwnerID,
-		"sent_at", eventMsg.SentAt,
+	c.log.Info("received event change from kafka",
+		"type", change.Type,
+		"event_id", change.EventID,
+		"version", change.Version,
+		"occurred_at", change.OccurredAt,
+		"title", snapshot.Title,
+		"description", snapshot.Description,
+		"start_time", snapshot.StartTime,
+		"end_time", snapshot.EndTime,
+		"owner_id", snapshot.OwnerID,
+		"sent_at", change.SentAt,
 		"offset", msg.Offset,
 		"partition", msg.Partition,
 	)
 
 	// Также выводим текст сообщения в лог
 	messageText := fmt.Sprintf(
-		"Event: %s (ID: %s) - %s. Starts: %s, Ends: %s. Sent at: %s",
-		eventMsg.Title,
-		eventMsg.ID,
-		eventMsg.Description,
-		eventMsg.StartTime.Format(time.RFC3339),
-		eventMsg.EndTime.Format(time.RFC3339),
-		eventMsg.SentAt.Format(time.RFC3339),
+		"Event %s: %s (ID: %s, version %d) - %s. Starts: %s, Ends: %s. Sent at: %s",
+		change.Type,
+		snapshot.Title,
+		change.EventID,
+		change.Version,
+		snapshot.Description,
+		snapshot.StartTime.Format(time.RFC3339),
+		snapshot.EndTime.Format(time.RFC3339),
+		change.SentAt.Format(time.RFC3339),
 	)
 
 	c.log.Info("event message text", "message", messageText)
@@ -130,15 +157,20 @@ func (c *Consumer) processMessage(msg kafka.Message) error {
 	return nil
 }
 
+// Health возвращает ошибку, если consumer не запущен или последнее чтение из Kafka не удалось.
+func (c *Consumer) Health() error {
+	return c.state.health()
+}
+
 // Stop останавливает consumer.
 func (c *Consumer) Stop() error {
-	if !c.running {
+	if !c.state.isRunning() {
 		return nil
 	}
 
 	c.log.Info("stopping kafka consumer")
 	close(c.stopCh)
-	c.running = false
+	c.state.setRunning(false)
 
 	if err := c.reader.Close(); err != nil {
 		return fmt.Errorf("failed to close kafka reader: %w", err)
@@ -2,15 +2,43 @@ package kafka
 
 import "time"
 
-// EventMessage представляет сообщение о событии для Kafka.
+// ChangeMessage — конверт уведомления об изменении события, которое публикуется в Kafka.
+// Before отсутствует у созданного события, After — у удалённого.
+type ChangeMessage struct {
+	Type       string        `json:"type"` // created / updated / deleted
+	EventID    string        `json:"event_id"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	Version    int64         `json:"version"`
+	Before     *EventMessage `json:"before"`
+	After      *EventMessage `json:"after"`
+	SentAt     time.Time     `json:"sent_at"`
+}
+
+// EventMessage — снимок события в ChangeMessage.
 type EventMessage struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	StartTime   time.Time `json:"start_time"`
-	EndTime     time.Time `json:"end_time"`
-	OwnerID     string    `json:"owner_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	SentAt      time.Time `json:"sent_at"`
+	ID               string      `json:"id"`
+	Title            string      `json:"title"`
+	Description      string      `json:"description"`
+	StartTime        time.Time   `json:"start_time"`
+	EndTime          time.Time   `json:"end_time"`
+	OwnerID          string      `json:"owner_id"`
+	CalendarID       string      `json:"calendar_id"`
+	RRule            string      `json:"rrule,omitempty"`
+	ExDates          []time.Time `json:"exdates,omitempty"`
+	RDates           []time.Time `json:"rdates,omitempty"`
+	TimeZone         string      `json:"timezone,omitempty"`
+	RecurringEventID string      `json:"recurring_event_id,omitempty"`
+	RecurrenceID     *time.Time  `json:"recurrence_id,omitempty"`
+	Reminders        []int       `json:"reminders,omitempty"`
+	Attendees        []Attendee  `json:"attendees,omitempty"`
+	ICalUID          string      `json:"ical_uid,omitempty"`
+	Version          int64       `json:"version"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// Attendee — участник события в EventMessage.
+type Attendee struct {
+	UserID string `json:"user_id"`
+	Status string `json:"status"`
 }
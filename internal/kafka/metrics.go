@@ -0,0 +1,35 @@
+package kafka
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	producedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "calendar",
+		Subsystem: "kafka_producer",
+		Name:      "messages_total",
+		Help:      "Messages sent to Kafka by topic and result (success, failure).",
+	}, []string{"topic", "result"})
+
+	consumedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "calendar",
+		Subsystem: "kafka_consumer",
+		Name:      "messages_total",
+		Help:      "Messages read from Kafka by topic and result (processed, failed).",
+	}, []string{"topic", "result"})
+
+	consumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "calendar",
+		Subsystem: "kafka_consumer",
+		Name:      "lag",
+		Help:      "Messages in the partition after the last one read by the consumer.",
+	}, []string{"topic", "partition"})
+)
+
+func partitionLabel(partition int) string {
+	return strconv.Itoa(partition)
+}
@@ -3,32 +3,51 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"calendar/internal/config"
 	"calendar/internal/logger"
-	"calendar/internal/services"
+	"calendar/internal/repos"
 
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// outboxBatchSize — сколько записей outbox забирается и отправляется в Kafka за раз.
+const outboxBatchSize = 100
+
+// outboxClaimTTL — на сколько записи outbox закрепляются за экземпляром, который их отправляет.
+// Если экземпляр упал, не отметив записи, по истечении этого времени их заберёт другой.
+const outboxClaimTTL = time.Minute
+
+// OutboxRepo — хранилище outbox, из которого producer пересылает изменения событий в Kafka.
+type OutboxRepo interface {
+	ClaimOutbox(ctx context.Context, limit int, ttl time.Duration) ([]repos.OutboxMessage, error)
+	MarkOutboxSent(ctx context.Context, ids []int64) error
+	ReleaseOutbox(ctx context.Context, ids []int64) error
+}
+
 // Producer отправляет сообщения в Kafka.
 type Producer struct {
-	writer  *kafka.Writer
-	log     logger.Logger
-	repo    services.EventsRepo
-	cfg     *config.Config
-	running bool
-	stopCh  chan struct{}
+	writer *kafka.Writer
+	log    logger.Logger
+	repo   OutboxRepo
+	cfg    *config.Config
+	state  workerState
+	stopCh chan struct{}
 }
 
 // NewProducer создаёт новый producer.
-func NewProducer(cfg *config.Config, log logger.Logger, repo services.EventsRepo) *Producer {
+func NewProducer(cfg *config.Config, log logger.Logger, repo OutboxRepo) *Producer {
 	writer := &kafka.Writer{
 		Addr:         kafka.TCP(cfg.Kafka.Brokers...),
 		Topic:        cfg.Kafka.Topic,
-		Balancer:     &kafka.LeastBytes{},
+		Balancer:     &kafka.Hash{},
+		BatchSize:    outboxBatchSize,
+		BatchTimeout: 10 * time.Millisecond,
 		WriteTimeout: 10 * time.Second,
 		RequiredAcks: kafka.RequireOne,
 	}
@@ -42,24 +61,24 @@ func NewProducer(cfg *config.Config, log logger.Logger, repo services.EventsRepo
 	}
 }
 
-// Start запускает producer, который периодически читает события из БД и отправляет их в Kafka.
+// Start запускает producer, который пересылает в Kafka неотправленные записи outbox.
 func (p *Producer) Start(ctx context.Context) error {
-	if p.running {
+	if p.state.isRunning() {
 		return fmt.Errorf("producer is already running")
 	}
 
-	p.running = true
-	p.log.Info("starting kafka producer")
+	p.state.setRunning(true)
+	p.log.Info("starting kafka producer", "poll_interval", p.cfg.Kafka.OutboxPollInterval)
 
-	// Запускаем горутину для периодической отправки событий
+	// Запускаем горутину для периодической пересылки outbox
 	go p.run(ctx)
 
 	return nil
 }
 
-// run периодически читает события из БД и отправляет их в Kafka.
+// run периодически пересылает outbox в Kafka.
 func (p *Producer) run(ctx context.Context) {
-	ticker := time.NewTicker(10 * time.Second) // Проверяем каждые 10 секунд
+	ticker := time.NewTicker(p.cfg.Kafka.OutboxPollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -71,80 +90,163 @@ func (p *Producer) run(ctx context.Context) {
 			p.log.Info("producer stopped")
 			return
 		case <-ticker.C:
-			if err := p.sendEventsFromDB(ctx); err != nil {
-				p.log.Error("failed to send events from DB", "error", err)
+			if err := p.relayOutbox(ctx); err != nil {
+				p.log.Error("failed to relay outbox", "error", err)
 			}
 		}
 	}
 }
 
-// sendEventsFromDB читает события из БД и отправляет их в Kafka.
-func (p *Producer) sendEventsFromDB(ctx context.Context) error {
-	events, err := p.repo.GetAllEvents(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get events from DB: %w", err)
+// relayOutbox отправляет в Kafka все неотправленные записи outbox, пачками по outboxBatchSize.
+// Пачка забирается из outbox, целиком уходит в Kafka одним WriteMessages и только потом
+// помечается доставленной, поэтому транзакции и блокировки базы не держатся, пока идёт запись в Kafka.
+// Записи, которые отправить не удалось, освобождаются и уйдут на следующем такте.
+func (p *Producer) relayOutbox(ctx context.Context) error {
+	for {
+		messages, err := p.repo.ClaimOutbox(ctx, outboxBatchSize, outboxClaimTTL)
+		if err != nil {
+			return fmt.Errorf("failed to claim outbox: %w", err)
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		sent, unsent, sendErr := p.sendOutbox(ctx, messages)
+		if err := p.repo.MarkOutboxSent(ctx, sent); err != nil {
+			return fmt.Errorf("failed to mark outbox sent: %w", err)
+		}
+		if len(sent) > 0 {
+			p.log.Info("outbox relayed to kafka", "count", len(sent))
+		}
+		if sendErr != nil {
+			if err := p.repo.ReleaseOutbox(ctx, unsent); err != nil {
+				return errors.Join(sendErr, fmt.Errorf("failed to release outbox: %w", err))
+			}
+			return sendErr
+		}
+		if len(messages) < outboxBatchSize {
+			return nil
+		}
 	}
+}
 
-	if len(events) == 0 {
-		p.log.Debug("no events to send")
-		return nil
+// sendOutbox отправляет записи outbox в Kafka одним вызовом WriteMessages и возвращает ID
+// доставленных и недоставленных записей. Если запись события не доставлена, более поздние
+// записи того же события тоже считаются недоставленными, даже если Kafka их приняла:
+// они уйдут повторно вслед за ней, и порядок изменений события не нарушится.
+func (p *Producer) sendOutbox(ctx context.Context, messages []repos.OutboxMessage) (sent, unsent []int64, err error) {
+	batch := make([]kafka.Message, 0, len(messages))
+	spans := make([]trace.Span, 0, len(messages))
+	for _, m := range messages {
+		msg, span, buildErr := p.outboxMessage(ctx, m)
+		if buildErr != nil {
+			// Испорченная запись и всё после неё остаются в outbox, как при ошибке отправки.
+			err = fmt.Errorf("failed to build outbox %d message: %w", m.ID, buildErr)
+			break
+		}
+		batch = append(batch, msg)
+		spans = append(spans, span)
+	}
+	for _, m := range messages[len(batch):] {
+		unsent = append(unsent, m.ID)
 	}
 
-	p.log.Info("sending events to kafka", "count", len(events))
+	writeCtx, cancel := context.WithTimeout(ctx, outboxClaimTTL/2)
+	defer cancel()
+	writeErr := p.writer.WriteMessages(writeCtx, batch...)
 
-	for _, event := range events {
-		msg := EventMessage{
-			ID:          event.ID,
-			Title:       event.Title,
-			Description: event.Description,
-			StartTime:   event.StartTime,
-			EndTime:     event.EndTime,
-			OwnerID:     event.OwnerID,
-			CreatedAt:   event.CreatedAt,
-			UpdatedAt:   event.UpdatedAt,
-			SentAt:      time.Now(),
+	var writeErrs kafka.WriteErrors
+	errors.As(writeErr, &writeErrs)
+	failed := make(map[string]bool)
+	for i, m := range messages[:len(batch)] {
+		msgErr := writeErr
+		if writeErrs != nil {
+			msgErr = writeErrs[i]
 		}
+		endSpan(spans[i], msgErr)
 
-		if err := p.sendMessage(ctx, msg); err != nil {
-			p.log.Error("failed to send event message", "event_id", event.ID, "error", err)
+		if msgErr != nil {
+			producedMessages.WithLabelValues(p.writer.Topic, "failure").Inc()
+			failed[m.EventID] = true
+		} else {
+			producedMessages.WithLabelValues(p.writer.Topic, "success").Inc()
+		}
+		if msgErr != nil || failed[m.EventID] {
+			unsent = append(unsent, m.ID)
 			continue
 		}
-
-		p.log.Debug("event sent to kafka", "event_id", event.ID, "title", event.Title)
+		sent = append(sent, m.ID)
+		p.log.Debug("event change sent to kafka", "event_id", m.EventID, "type", m.Type, "outbox_id", m.ID)
 	}
 
-	return nil
+	if writeErr != nil {
+		err = errors.Join(fmt.Errorf("failed to write messages to kafka: %w", writeErr), err)
+	}
+	return sent, unsent, err
 }
 
-// sendMessage отправляет одно сообщение в Kafka.
-func (p *Producer) sendMessage(ctx context.Context, msg EventMessage) error {
+// outboxMessage превращает запись outbox в сообщение Kafka с ChangeMessage и начинает спан его отправки.
+// Ключ сообщения — ID события, чтобы все изменения одного события шли в одну партицию по порядку.
+// Контекст трассировки запроса, записавшего изменение, передаётся consumer'у в заголовках сообщения.
+func (p *Producer) outboxMessage(ctx context.Context, m repos.OutboxMessage) (kafka.Message, trace.Span, error) {
+	msg := ChangeMessage{
+		Type:       string(m.Type),
+		EventID:    m.EventID,
+		OccurredAt: m.OccurredAt,
+		Version:    m.Version,
+		SentAt:     time.Now(),
+	}
+
+	if m.Before != nil {
+		msg.Before = &EventMessage{}
+		if err := json.Unmarshal(m.Before, msg.Before); err != nil {
+			return kafka.Message{}, nil, fmt.Errorf("failed to unmarshal before: %w", err)
+		}
+	}
+	if m.After != nil {
+		msg.After = &EventMessage{}
+		if err := json.Unmarshal(m.After, msg.After); err != nil {
+			return kafka.Message{}, nil, fmt.Errorf("failed to unmarshal after: %w", err)
+		}
+	}
+
 	body, err := json.Marshal(msg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return kafka.Message{}, nil, fmt.Errorf("failed to marshal message: %w", err)
 	}
 
 	kafkaMsg := kafka.Message{
-		Key:   []byte(msg.ID),
+		Key:   []byte(msg.EventID),
 		Value: body,
 		Time:  time.Now(),
+		Headers: []kafka.Header{
+			{Key: "type", Value: []byte(msg.Type)},
+		},
 	}
 
-	if err := p.writer.WriteMessages(ctx, kafkaMsg); err != nil {
-		return fmt.Errorf("failed to write message to kafka: %w", err)
-	}
+	spanCtx, span := startSpan(outboxTraceContext(ctx, m.TraceContext), "publish", trace.SpanKindProducer, p.writer.Topic, kafkaMsg.Key)
+	otel.GetTextMapPropagator().Inject(spanCtx, headerCarrier{&kafkaMsg.Headers})
+	return kafkaMsg, span, nil
+}
 
+// Health возвращает ошибку, если producer не запущен. Неудачная пересылка outbox на готовность
+// не влияет: события копятся в outbox и уйдут в Kafka, когда она снова станет доступна.
+func (p *Producer) Health() error {
+	if !p.state.isRunning() {
+		return errNotRunning
+	}
 	return nil
 }
 
 // Stop останавливает producer.
 func (p *Producer) Stop() error {
-	if !p.running {
+	if !p.state.isRunning() {
 		return nil
 	}
 
 	p.log.Info("stopping kafka producer")
 	close(p.stopCh)
-	p.running = false
+	p.state.setRunning(false)
 
 	if err := p.writer.Close(); err != nil {
 		return fmt.Errorf("failed to close kafka writer: %w", err)
@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"errors"
+	"sync"
+)
+
+var errNotRunning = errors.New("not running")
+
+// workerState — состояние фоновой горутины producer'а или consumer'а для проверки готовности:
+// запущена ли она и чем закончилась последняя попытка обмена с Kafka.
+type workerState struct {
+	mu      sync.Mutex
+	running bool
+	lastErr error
+}
+
+func (s *workerState) isRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+func (s *workerState) setRunning(running bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = running
+}
+
+// report запоминает результат последней попытки; nil — Kafka снова доступна.
+func (s *workerState) report(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+}
+
+// health возвращает ошибку, если горутина не запущена или последняя попытка не удалась.
+func (s *workerState) health() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return errNotRunning
+	}
+	return s.lastErr
+}
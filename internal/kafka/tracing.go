@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("calendar/internal/kafka")
+
+// headerCarrier даёт propagator'у OpenTelemetry читать и писать контекст трассировки
+// в заголовки сообщения Kafka.
+type headerCarrier struct {
+	headers *[]kafka.Header
+}
+
+func (c headerCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	for i, h := range *c.headers {
+		if h.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, h := range *c.headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// outboxTraceContext восстанавливает в ctx трассу запроса, записавшего изменение в outbox.
+// Испорченный или пустой контекст просто игнорируется.
+func outboxTraceContext(ctx context.Context, traceContext []byte) context.Context {
+	if traceContext == nil {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{}
+	if err := json.Unmarshal(traceContext, &carrier); err != nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// startSpan начинает спан отправки или обработки сообщения с ключом key в топике topic.
+func startSpan(ctx context.Context, operation string, kind trace.SpanKind, topic string, key []byte) (context.Context, trace.Span) {
+	return tracer.Start(ctx, topic+" "+operation,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.operation.name", operation),
+			attribute.String("messaging.destination.name", topic),
+			attribute.String("messaging.kafka.message.key", string(key)),
+		),
+	)
+}
+
+// endSpan завершает спан, отмечая в нём ошибку err, если она есть.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
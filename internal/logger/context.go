@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+type scopeKey struct{}
+
+// scope — логгер одного запроса. Он общий для всех слоёв, через которые проходит запрос,
+// поэтому атрибуты, добавленные внутри (например, пользователь после аутентификации),
+// попадают и в записи внешних слоёв.
+type scope struct {
+	mu  sync.Mutex
+	log Logger
+}
+
+// NewContext возвращает ctx с логгером запроса log.
+func NewContext(ctx context.Context, log Logger) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{log: log})
+}
+
+// FromContext возвращает логгер запроса из ctx, а вне запроса — логгер по умолчанию.
+// Им пользуются хендлеры, сервисы и репозитории, чтобы записи несли request_id.
+func FromContext(ctx context.Context) Logger {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return slog.Default()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log
+}
+
+// Annotate добавляет атрибуты args ко всем последующим записям логгера запроса в ctx.
+// Вне запроса ничего не делает.
+func Annotate(ctx context.Context, args ...any) {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = s.log.With(args...)
+}
@@ -1,16 +1,23 @@
 package migrations
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/file"
 )
 
+// sourceURL — каталог с файлами миграций относительно рабочего каталога сервиса.
+const sourceURL = "file://migrations"
+
 func Up(dsn string) error {
 	m, err := migrate.New(
-		"file://migrations",
+		sourceURL,
 		dsn,
 	)
 	if err != nil {
@@ -22,3 +29,53 @@ func Up(dsn string) error {
 	}
 	return nil
 }
+
+// Latest возвращает версию последней миграции из каталога миграций —
+// версию схемы, которую ожидает этот код.
+func Latest() (uint, error) {
+	src, err := (&file.File{}).Open(sourceURL)
+	if err != nil {
+		return 0, fmt.Errorf("open migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("read migrations: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+// Check проверяет, что схема базы db доведена хотя бы до версии want и последняя миграция
+// не оборвалась на середине (dirty). Схема новее want — не ошибка: при выкатке новая версия
+// сервиса применяет свои миграции, пока старые экземпляры ещё обслуживают запросы.
+func Check(ctx context.Context, db *sql.DB, want uint) error {
+	var (
+		version uint
+		dirty   bool
+	)
+	err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no migrations applied, want version %d", want)
+	}
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if version < want {
+		return fmt.Errorf("schema version %d, want %d", version, want)
+	}
+	return nil
+}
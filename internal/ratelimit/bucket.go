@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// bucket — корзина токенов одного клиента.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// buckets — корзины клиентов одной группы маршрутов: в каждой до burst токенов,
+// которые восполняются со скоростью rate в секунду.
+type buckets struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	byClient  map[string]*bucket
+	lastSweep time.Time
+}
+
+func newBuckets(rate float64, burst int) *buckets {
+	return &buckets{
+		rate:     rate,
+		burst:    float64(burst),
+		byClient: make(map[string]*bucket),
+	}
+}
+
+// decision — итог попытки запроса клиента.
+type decision struct {
+	allowed bool
+	// remaining — сколько запросов подряд клиент ещё может сделать.
+	remaining int
+	// retryAfter — через сколько появится следующий токен (для отклонённого запроса).
+	retryAfter time.Duration
+	// resetAfter — через сколько корзина снова заполнится.
+	resetAfter time.Duration
+}
+
+// take снимает токен из корзины клиента, если он есть.
+func (b *buckets) take(client string, now time.Time) decision {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep(now)
+
+	bk, ok := b.byClient[client]
+	if !ok {
+		bk = &bucket{tokens: b.burst, last: now}
+		b.byClient[client] = bk
+	}
+	if elapsed := now.Sub(bk.last).Seconds(); elapsed > 0 {
+		bk.tokens = math.Min(b.burst, bk.tokens+elapsed*b.rate)
+	}
+	bk.last = now
+
+	d := decision{allowed: bk.tokens >= 1}
+	if d.allowed {
+		bk.tokens--
+	} else {
+		d.retryAfter = b.after(1 - bk.tokens)
+	}
+	d.remaining = int(bk.tokens)
+	d.resetAfter = b.after(b.burst - bk.tokens)
+	return d
+}
+
+// after возвращает, за сколько восполнится tokens токенов.
+func (b *buckets) after(tokens float64) time.Duration {
+	return time.Duration(tokens / b.rate * float64(time.Second))
+}
+
+// sweep раз в минуту удаляет корзины, которые успели заполниться: они не отличаются от новых.
+func (b *buckets) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < time.Minute {
+		return
+	}
+	b.lastSweep = now
+
+	full := b.after(b.burst)
+	for client, bk := range b.byClient {
+		if now.Sub(bk.last) >= full {
+			delete(b.byClient, client)
+		}
+	}
+}
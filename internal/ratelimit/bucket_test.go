@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBucketsTake(t *testing.T) {
+	t0 := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	// rate 2 в секунду, burst 3: токен восполняется за 500ms.
+	steps := []struct {
+		name          string
+		client        string
+		at            time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+		wantReset     time.Duration
+	}{
+		{"new client gets a full bucket", "a", 0, true, 2, 0, 500 * time.Millisecond},
+		{"burst continues", "a", 0, true, 1, 0, time.Second},
+		{"last token of the burst", "a", 0, true, 0, 0, 1500 * time.Millisecond},
+		{"empty bucket is rejected", "a", 0, false, 0, 500 * time.Millisecond, 1500 * time.Millisecond},
+		{"other clients are not affected", "b", 0, true, 2, 0, 500 * time.Millisecond},
+		{"half a token is not enough", "a", 250 * time.Millisecond, false, 0, 250 * time.Millisecond, 1250 * time.Millisecond},
+		{"a token is refilled", "a", 500 * time.Millisecond, true, 0, 0, 1500 * time.Millisecond},
+		{"refill is capped at burst", "a", time.Minute, true, 2, 0, 500 * time.Millisecond},
+		{"clock going back refills nothing", "a", 30 * time.Second, true, 1, 0, time.Second},
+	}
+
+	b := newBuckets(2, 3)
+	for _, s := range steps {
+		d := b.take(s.client, t0.Add(s.at))
+		if d.allowed != s.wantAllowed || d.remaining != s.wantRemaining ||
+			d.retryAfter != s.wantRetry || d.resetAfter != s.wantReset {
+			t.Errorf("%s: take = %+v, want allowed=%v remaining=%d retryAfter=%v resetAfter=%v",
+				s.name, d, s.wantAllowed, s.wantRemaining, s.wantRetry, s.wantReset)
+		}
+	}
+}
+
+func TestBucketsSweep(t *testing.T) {
+	t0 := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	// rate 1 в секунду, burst 5: пустая корзина заполняется за 5s.
+	steps := []struct {
+		name        string
+		client      string
+		at          time.Duration
+		wantClients []string
+	}{
+		{"first request sweeps an empty map", "a", 0, []string{"a"}},
+		{"second client", "b", 58 * time.Second, []string{"a", "b"}},
+		{"no sweep within a minute", "c", 59 * time.Second, []string{"a", "b", "c"}},
+		{"sweep drops refilled buckets only", "d", 61 * time.Second, []string{"b", "c", "d"}},
+		{"next sweep a minute later", "d", 2 * time.Minute, []string{"b", "c", "d"}},
+		{"all idle buckets are dropped", "e", 2*time.Minute + time.Minute, []string{"e"}},
+	}
+
+	b := newBuckets(1, 5)
+	for _, s := range steps {
+		b.take(s.client, t0.Add(s.at))
+
+		var got []string
+		for client := range b.byClient {
+			got = append(got, client)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, s.wantClients) {
+			t.Errorf("%s: clients = %v, want %v", s.name, got, s.wantClients)
+		}
+	}
+}
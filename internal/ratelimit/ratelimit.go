@@ -0,0 +1,178 @@
+// Package ratelimit ограничивает частоту запросов к HTTP API для каждого клиента.
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar/internal/auth"
+	"calendar/internal/config"
+	"calendar/internal/logger"
+)
+
+// group — группа маршрутов со своим лимитом.
+type group struct {
+	rule config.RateLimitRule
+	// buckets — корзины клиентов (ключей API и пользователей), ipBuckets — корзины IP.
+	buckets   *buckets
+	ipBuckets *buckets
+}
+
+func (g *group) matches(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, g.rule.Prefix) &&
+		(len(g.rule.Methods) == 0 || slices.Contains(g.rule.Methods, r.Method))
+}
+
+// Limiter ограничивает запросы алгоритмом token bucket: у каждого клиента и каждого IP
+// в каждой группе маршрутов своя корзина.
+type Limiter struct {
+	log            logger.Logger
+	enabled        bool
+	groups         []*group
+	trustedProxies int
+}
+
+// NewLimiter создаёт Limiter по настройкам cfg.RateLimit.
+func NewLimiter(cfg *config.Config, log logger.Logger) (*Limiter, error) {
+	if cfg.RateLimit.TrustedProxies < 0 {
+		return nil, fmt.Errorf("rate limit: trusted_proxies must not be negative")
+	}
+	l := &Limiter{
+		log:            log,
+		enabled:        cfg.RateLimit.Enabled,
+		trustedProxies: cfg.RateLimit.TrustedProxies,
+	}
+
+	for _, rule := range cfg.RateLimit.Groups {
+		if rule.IPRate == 0 {
+			rule.IPRate = rule.Rate
+		}
+		if rule.IPBurst == 0 {
+			rule.IPBurst = rule.Burst
+		}
+		if rule.Rate <= 0 || rule.Burst < 1 || rule.IPRate <= 0 || rule.IPBurst < 1 {
+			return nil, fmt.Errorf("rate limit group %q: rates and bursts must be positive", rule.Name)
+		}
+		l.groups = append(l.groups, &group{
+			rule:      rule,
+			buckets:   newBuckets(rule.Rate, rule.Burst),
+			ipBuckets: newBuckets(rule.IPRate, rule.IPBurst),
+		})
+	}
+	return l, nil
+}
+
+// IPMiddleware отклоняет с 429 запросы с IP, исчерпавшего лимит своей группы маршрутов.
+// Стоит перед аутентификацией, чтобы ограничить и запросы, которые она отклонит
+// (перебор ключей API и токенов), и анонимные запросы.
+func (l *Limiter) IPMiddleware(next http.Handler) http.Handler {
+	if !l.enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g := l.group(r)
+		if g == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		l.serve(w, r, next, g.rule.Name, g.rule.IPBurst, g.ipBuckets, "ip:"+l.clientIP(r))
+	})
+}
+
+// Middleware отклоняет с 429 запросы клиентов, исчерпавших лимит своей группы маршрутов,
+// и сообщает остаток лимита в заголовках X-RateLimit-*. Клиент — ключ API или пользователь,
+// поэтому Middleware должен стоять после аутентификации; анонимные запросы ограничивает
+// только IPMiddleware.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if !l.enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g := l.group(r)
+		client, ok := l.client(r)
+		if g == nil || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		l.serve(w, r, next, g.rule.Name, g.rule.Burst, g.buckets, client)
+	})
+}
+
+// group возвращает первую группу маршрутов, в которую попадает r, или nil.
+func (l *Limiter) group(r *http.Request) *group {
+	i := slices.IndexFunc(l.groups, func(g *group) bool { return g.matches(r) })
+	if i < 0 {
+		return nil
+	}
+	return l.groups[i]
+}
+
+// serve снимает токен клиента client из b и передаёт запрос next или отвечает 429.
+func (l *Limiter) serve(w http.ResponseWriter, r *http.Request, next http.Handler, name string, burst int, b *buckets, client string) {
+	d := b.take(client, time.Now())
+
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(burst))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(d.remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(seconds(d.resetAfter)))
+
+	if !d.allowed {
+		l.log.Debug("rate limit exceeded", "group", name, "client", client)
+		h.Set("Retry-After", strconv.Itoa(seconds(d.retryAfter)))
+		h.Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "rate limit exceeded"})
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+// client возвращает, чей лимит расходует запрос: ключа API или пользователя;
+// false — запрос анонимный.
+func (l *Limiter) client(r *http.Request) (string, bool) {
+	if id, ok := auth.APIKeyIDFrom(r.Context()); ok {
+		return "key:" + id, true
+	}
+	if userID, ok := auth.UserFrom(r.Context()); ok {
+		return "user:" + userID, true
+	}
+	return "", false
+}
+
+// clientIP возвращает IP клиента. За trustedProxies своими прокси это запись X-Forwarded-For,
+// которую дописал самый дальний из них: каждый прокси добавляет справа адрес, с которого
+// к нему пришли, а всё, что левее, прислал сам клиент.
+func (l *Limiter) clientIP(r *http.Request) string {
+	if l.trustedProxies > 0 {
+		var hops []string
+		for _, h := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(h, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		// Записей меньше, чем прокси, — запрос пришёл не через все прокси; самая левая
+		// запись всё равно дописана одним из них.
+		if len(hops) > 0 {
+			return hops[max(len(hops)-l.trustedProxies, 0)]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
+
+// seconds округляет d вверх до целых секунд, как принято в Retry-After.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies int
+		forwardedFor   []string
+		want           string
+	}{
+		{"no proxies ignores the header", 0, []string{"203.0.113.7"}, "192.0.2.1"},
+		{"no header", 1, nil, "192.0.2.1"},
+		{"one proxy", 1, []string{"203.0.113.7"}, "203.0.113.7"},
+		{"spoofed entry before the proxy hop", 1, []string{"10.0.0.1, 203.0.113.7"}, "203.0.113.7"},
+		{"two proxies", 2, []string{"10.0.0.1, 203.0.113.7, 198.51.100.2"}, "203.0.113.7"},
+		{"hops split across header lines", 2, []string{"10.0.0.1", "203.0.113.7", "198.51.100.2"}, "203.0.113.7"},
+		{"fewer hops than proxies", 3, []string{"203.0.113.7, 198.51.100.2"}, "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Limiter{trustedProxies: tt.trustedProxies}
+			r := httptest.NewRequest("GET", "/api/events", nil)
+			r.RemoteAddr = "192.0.2.1:54321"
+			for _, v := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			if got := l.clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package reminders
+
+import "time"
+
+// MessageTypeDue — тип сообщения о наступившем напоминании.
+const MessageTypeDue = "reminder.due"
+
+// ReminderMessage — сообщение о напоминании, которое публикуется в топик напоминаний.
+type ReminderMessage struct {
+	Type          string    `json:"type"`
+	ReminderID    int64     `json:"reminder_id"`
+	EventID       string    `json:"event_id"`
+	OwnerID       string    `json:"owner_id"`
+	Title         string    `json:"title"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	MinutesBefore int       `json:"minutes_before"`
+	FireAt        time.Time `json:"fire_at"`
+	SentAt        time.Time `json:"sent_at"`
+}
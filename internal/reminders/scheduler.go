@@ -0,0 +1,304 @@
+package reminders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"calendar/internal/config"
+	"calendar/internal/logger"
+	"calendar/internal/repos"
+	"calendar/internal/services"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// relayBatchSize — сколько напоминаний забирается и отправляется в Kafka за раз.
+const relayBatchSize = 100
+
+// relayClaimTTL — на сколько напоминания закрепляются за экземпляром, который их отправляет.
+// Если экземпляр упал, не отметив напоминания, по истечении этого времени их заберёт другой.
+const relayClaimTTL = time.Minute
+
+// Repo — хранилище, из которого планировщик берёт события и в котором отмечает напоминания.
+type Repo interface {
+	ListEventsWithReminders(ctx context.Context, from, to, now time.Time) ([]repos.Event, error)
+	AdvanceReminders(ctx context.Context, next []repos.NextReminder) error
+	ScheduleReminders(ctx context.Context, reminders []repos.Reminder) (int, error)
+	ClaimReminders(ctx context.Context, limit int, ttl time.Duration) ([]repos.Reminder, error)
+	MarkRemindersSent(ctx context.Context, ids []int64) error
+	ReleaseReminders(ctx context.Context, ids []int64) error
+}
+
+// Scheduler периодически находит наступившие напоминания и отправляет их в Kafka.
+//
+// Каждое напоминание сначала сохраняется в reminder_deliveries с уникальным ключом
+// (событие, вхождение, за сколько минут), а затем пересылается и помечается отправленным,
+// поэтому после перезапуска ничего не срабатывает повторно. Серия разворачивается,
+// только когда наступило её ближайшее напоминание; после планирования оно сдвигается
+// на следующее (repos.NextReminder), так что бесконечные серии не читаются на каждом такте.
+type Scheduler struct {
+	writer  *kafka.Writer
+	log     logger.Logger
+	repo    Repo
+	cfg     *config.Config
+	running bool
+	stopCh  chan struct{}
+}
+
+// NewScheduler создаёт новый планировщик напоминаний.
+func NewScheduler(cfg *config.Config, log logger.Logger, repo Repo) *Scheduler {
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Kafka.Brokers...),
+		Topic:        cfg.Kafka.RemindersTopic,
+		Balancer:     &kafka.LeastBytes{},
+		BatchSize:    relayBatchSize,
+		BatchTimeout: 10 * time.Millisecond,
+		WriteTimeout: 10 * time.Second,
+		RequiredAcks: kafka.RequireOne,
+	}
+
+	return &Scheduler{
+		writer: writer,
+		log:    log,
+		repo:   repo,
+		cfg:    cfg,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start запускает планировщик.
+func (s *Scheduler) Start(ctx context.Context) error {
+	if s.running {
+		return fmt.Errorf("reminder scheduler is already running")
+	}
+
+	s.running = true
+	s.log.Info("starting reminder scheduler",
+		"topic", s.cfg.Kafka.RemindersTopic,
+		"poll_interval", s.cfg.Reminders.PollInterval,
+	)
+
+	go s.run(ctx)
+
+	return nil
+}
+
+// run периодически планирует и пересылает напоминания.
+func (s *Scheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Reminders.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info("reminder scheduler context cancelled")
+			return
+		case <-s.stopCh:
+			s.log.Info("reminder scheduler stopped")
+			return
+		case <-ticker.C:
+			if err := s.tick(ctx, time.Now()); err != nil {
+				s.log.Error("reminder scheduler tick failed", "error", err)
+			}
+		}
+	}
+}
+
+// tick планирует напоминания, наступившие к моменту now, и отправляет все неотправленные.
+func (s *Scheduler) tick(ctx context.Context, now time.Time) error {
+	due, next, err := s.collectDue(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to collect due reminders: %w", err)
+	}
+
+	scheduled, err := s.repo.ScheduleReminders(ctx, due)
+	if err != nil {
+		return fmt.Errorf("failed to schedule reminders: %w", err)
+	}
+	if scheduled > 0 {
+		s.log.Info("reminders scheduled", "count", scheduled)
+	}
+
+	// Только после сохранения напоминаний: иначе при ошибке серия до своего следующего
+	// напоминания не прочиталась бы и текущие были бы потеряны.
+	if err := s.repo.AdvanceReminders(ctx, next); err != nil {
+		return fmt.Errorf("failed to advance reminders: %w", err)
+	}
+
+	return s.relay(ctx)
+}
+
+// collectDue находит вхождения, которые ещё не начались (с допуском MissedGrace)
+// и напоминание о которых уже должно было сработать, а для прочитанных серий — их
+// следующее напоминание.
+func (s *Scheduler) collectDue(ctx context.Context, now time.Time) ([]repos.Reminder, []repos.NextReminder, error) {
+	from := now.Add(-s.cfg.Reminders.MissedGrace)
+	to := now.Add(services.MaxReminderLead)
+
+	events, err := s.repo.ListEventsWithReminders(ctx, from, to, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	occurrences, err := services.ExpandEvents(events, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next []repos.NextReminder
+	for _, e := range events {
+		if e.RRule == "" {
+			continue
+		}
+		at, err := services.NextReminderAt(e, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		next = append(next, repos.NextReminder{EventID: e.ID, Version: e.Version, At: at})
+	}
+
+	var due []repos.Reminder
+	for _, o := range occurrences {
+		if o.StartTime.Before(from) {
+			continue
+		}
+		for _, minutes := range o.Reminders {
+			fireAt := o.StartTime.Add(-time.Duration(minutes) * time.Minute)
+			if fireAt.After(now) {
+				continue
+			}
+			due = append(due, repos.Reminder{
+				EventID:         o.ID,
+				OccurrenceStart: o.StartTime,
+				MinutesBefore:   minutes,
+				FireAt:          fireAt,
+			})
+		}
+	}
+	return due, next, nil
+}
+
+// relay пересылает все неотправленные напоминания пачками по relayBatchSize.
+// Пачка забирается из базы, целиком уходит в Kafka одним WriteMessages и только потом
+// помечается отправленной, поэтому транзакции базы не держатся, пока идёт запись в Kafka.
+func (s *Scheduler) relay(ctx context.Context) error {
+	for {
+		reminders, err := s.repo.ClaimReminders(ctx, relayBatchSize, relayClaimTTL)
+		if err != nil {
+			return fmt.Errorf("failed to claim reminders: %w", err)
+		}
+		if len(reminders) == 0 {
+			return nil
+		}
+
+		sent, unsent, sendErr := s.sendReminders(ctx, reminders)
+		if err := s.repo.MarkRemindersSent(ctx, sent); err != nil {
+			return fmt.Errorf("failed to mark reminders sent: %w", err)
+		}
+		if len(sent) > 0 {
+			s.log.Info("reminders sent to kafka", "count", len(sent))
+		}
+		if sendErr != nil {
+			if err := s.repo.ReleaseReminders(ctx, unsent); err != nil {
+				return errors.Join(sendErr, fmt.Errorf("failed to release reminders: %w", err))
+			}
+			return sendErr
+		}
+		if len(reminders) < relayBatchSize {
+			return nil
+		}
+	}
+}
+
+// sendReminders отправляет напоминания в Kafka одним вызовом WriteMessages и возвращает ID
+// отправленных и неотправленных напоминаний.
+func (s *Scheduler) sendReminders(ctx context.Context, reminders []repos.Reminder) (sent, unsent []int64, err error) {
+	batch := make([]kafka.Message, 0, len(reminders))
+	for _, r := range reminders {
+		msg, err := reminderMessage(r)
+		if err != nil {
+			return nil, ids(reminders), err
+		}
+		batch = append(batch, msg)
+	}
+
+	writeCtx, cancel := context.WithTimeout(ctx, relayClaimTTL/2)
+	defer cancel()
+	writeErr := s.writer.WriteMessages(writeCtx, batch...)
+	if writeErr == nil {
+		return ids(reminders), nil, nil
+	}
+
+	var writeErrs kafka.WriteErrors
+	if !errors.As(writeErr, &writeErrs) {
+		return nil, ids(reminders), fmt.Errorf("failed to write reminders to kafka: %w", writeErr)
+	}
+	for i, r := range reminders {
+		if writeErrs[i] != nil {
+			unsent = append(unsent, r.ID)
+			continue
+		}
+		sent = append(sent, r.ID)
+	}
+	return sent, unsent, fmt.Errorf("failed to write %d of %d reminders to kafka: %w", len(unsent), len(reminders), writeErr)
+}
+
+// reminderMessage превращает напоминание в сообщение reminder.due для Kafka.
+func reminderMessage(r repos.Reminder) (kafka.Message, error) {
+	msg := ReminderMessage{
+		Type:          MessageTypeDue,
+		ReminderID:    r.ID,
+		EventID:       r.EventID,
+		OwnerID:       r.OwnerID,
+		Title:         r.Title,
+		StartTime:     r.OccurrenceStart,
+		EndTime:       r.OccurrenceEnd,
+		MinutesBefore: r.MinutesBefore,
+		FireAt:        r.FireAt,
+		SentAt:        time.Now(),
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal reminder %d: %w", r.ID, err)
+	}
+
+	return kafka.Message{
+		Key:   []byte(r.EventID),
+		Value: body,
+		Time:  time.Now(),
+		Headers: []kafka.Header{
+			{Key: "type", Value: []byte(msg.Type)},
+			{Key: "reminder_id", Value: []byte(strconv.FormatInt(r.ID, 10))},
+		},
+	}, nil
+}
+
+// ids возвращает ID напоминаний reminders.
+func ids(reminders []repos.Reminder) []int64 {
+	result := make([]int64, 0, len(reminders))
+	for _, r := range reminders {
+		result = append(result, r.ID)
+	}
+	return result
+}
+
+// Stop останавливает планировщик.
+func (s *Scheduler) Stop() error {
+	if !s.running {
+		return nil
+	}
+
+	s.log.Info("stopping reminder scheduler")
+	close(s.stopCh)
+	s.running = false
+
+	if err := s.writer.Close(); err != nil {
+		return fmt.Errorf("failed to close kafka writer: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package repos
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey — ключ API, с которым сервисы и боты обращаются к API от имени OwnerID.
+// Сам ключ не хранится, только его хэш.
+type APIKey struct {
+	ID      string
+	OwnerID string
+	Name    string
+	// Prefix — начало ключа, по которому его можно узнать в списке.
+	Prefix    string
+	Hash      string
+	Scopes    []string
+	CreatedAt time.Time
+	// RevokedAt — когда ключ отозван; nil — ключ действует.
+	RevokedAt *time.Time
+}
+
+const apiKeyColumns = `id, owner_id, name, prefix, key_hash, scopes, created_at, revoked_at`
+
+func scanAPIKey(row rowScanner) (APIKey, error) {
+	var (
+		k      APIKey
+		scopes pq.StringArray
+	)
+	err := row.Scan(&k.ID, &k.OwnerID, &k.Name, &k.Prefix, &k.Hash, &scopes, &k.CreatedAt, &k.RevokedAt)
+	if err != nil {
+		return APIKey{}, err
+	}
+	k.Scopes = scopes
+	return k, nil
+}
+
+// CreateAPIKey сохраняет новый ключ и заполняет CreatedAt.
+func (s *PGEventStorage) CreateAPIKey(ctx context.Context, k *APIKey) error {
+	const query = `
+		INSERT INTO api_keys (id, owner_id, name, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+
+	return s.db.QueryRowContext(ctx, query, k.ID, k.OwnerID, k.Name, k.Prefix, k.Hash, pq.Array(k.Scopes)).
+		Scan(&k.CreatedAt)
+}
+
+// GetAPIKey возвращает ключ по ID (в том числе отозванный) или sql.ErrNoRows.
+func (s *PGEventStorage) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	const query = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+
+	k, err := scanAPIKey(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// GetAPIKeyByHash возвращает действующий ключ по хэшу или sql.ErrNoRows.
+func (s *PGEventStorage) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	const query = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	k, err := scanAPIKey(s.db.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListAPIKeys возвращает ключи владельца (пустой ownerID — всех владельцев) в порядке создания,
+// включая отозванные.
+func (s *PGEventStorage) ListAPIKeys(ctx context.Context, ownerID string) ([]APIKey, error) {
+	const query = `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE $1 = '' OR owner_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает действующий ключ; если такого нет — sql.ErrNoRows.
+func (s *PGEventStorage) RevokeAPIKey(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	return expectRows(res)
+}
@@ -0,0 +1,153 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrAttendeeExists — пользователь уже приглашён на событие.
+var ErrAttendeeExists = errors.New("attendee already exists")
+
+// Attendee — приглашённый на событие пользователь и его ответ (RSVP).
+type Attendee struct {
+	EventID   string
+	UserID    string
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// AddAttendee приглашает пользователя на событие. Статус берётся из a.Status или,
+// если он пуст, из значения по умолчанию; CreatedAt/UpdatedAt заполняются.
+// Если пользователь уже приглашён — ErrAttendeeExists, если события нет — sql.ErrNoRows.
+func (s *PGEventStorage) AddAttendee(ctx context.Context, a *Attendee) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := lockSnapshot(ctx, tx, a.EventID)
+		if err != nil {
+			return err
+		}
+
+		const query = `
+			INSERT INTO event_attendees (event_id, user_id, status)
+			VALUES ($1, $2, COALESCE(NULLIF($3, ''), 'needs-action'))
+			ON CONFLICT (event_id, user_id) DO NOTHING
+			RETURNING status, created_at, updated_at
+		`
+
+		err = tx.QueryRowContext(ctx, query, a.EventID, a.UserID, a.Status).
+			Scan(&a.Status, &a.CreatedAt, &a.UpdatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAttendeeExists
+		}
+		if err != nil {
+			return err
+		}
+
+		return touchAndRecord(ctx, tx, a.EventID, before)
+	})
+}
+
+// UpdateAttendeeStatus меняет ответ участника a.EventID/a.UserID на a.Status и заполняет остальные поля.
+// Если участника нет — sql.ErrNoRows.
+func (s *PGEventStorage) UpdateAttendeeStatus(ctx context.Context, a *Attendee) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := lockSnapshot(ctx, tx, a.EventID)
+		if err != nil {
+			return err
+		}
+
+		const query = `
+			UPDATE event_attendees
+			SET
+				status     = $3,
+				updated_at = NOW()
+			WHERE event_id = $1 AND user_id = $2
+			RETURNING created_at, updated_at
+		`
+
+		err = tx.QueryRowContext(ctx, query, a.EventID, a.UserID, a.Status).
+			Scan(&a.CreatedAt, &a.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return touchAndRecord(ctx, tx, a.EventID, before)
+	})
+}
+
+// RemoveAttendee отзывает приглашение. Если участника нет — sql.ErrNoRows.
+func (s *PGEventStorage) RemoveAttendee(ctx context.Context, eventID, userID string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := lockSnapshot(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+
+		const query = `DELETE FROM event_attendees WHERE event_id = $1 AND user_id = $2`
+
+		res, err := tx.ExecContext(ctx, query, eventID, userID)
+		if err != nil {
+			return err
+		}
+		if err := expectRows(res); err != nil {
+			return err
+		}
+
+		return touchAndRecord(ctx, tx, eventID, before)
+	})
+}
+
+// touchAndRecord увеличивает версию события после изменения его участников
+// и записывает изменение в outbox.
+func touchAndRecord(ctx context.Context, tx *sql.Tx, eventID string, before []byte) error {
+	const query = `
+		UPDATE events
+		SET
+			version    = version + 1,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := tx.ExecContext(ctx, query, eventID); err != nil {
+		return err
+	}
+	return recordChange(ctx, tx, ChangeUpdated, eventID, before)
+}
+
+// ListAttendees возвращает участников событий eventIDs, сгруппированных по ID события.
+func (s *PGEventStorage) ListAttendees(ctx context.Context, eventIDs []string) (map[string][]Attendee, error) {
+	res := make(map[string][]Attendee)
+	if len(eventIDs) == 0 {
+		return res, nil
+	}
+
+	const query = `
+		SELECT event_id, user_id, status, created_at, updated_at
+		FROM event_attendees
+		WHERE event_id = ANY($1::uuid[])
+		ORDER BY event_id, user_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(eventIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a Attendee
+		if err := rows.Scan(&a.EventID, &a.UserID, &a.Status, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		res[a.EventID] = append(res[a.EventID], a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,183 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrDefaultCalendar — календарь по умолчанию нельзя удалить.
+var ErrDefaultCalendar = errors.New("default calendar cannot be deleted")
+
+// DefaultCalendarName — название календаря по умолчанию, который создаётся владельцу автоматически.
+const DefaultCalendarName = "Default"
+
+// Calendar — календарь пользователя, в котором лежат его события.
+type Calendar struct {
+	ID      string
+	OwnerID string
+	Name    string
+	// Color — цвет в виде "#rrggbb"; пустая строка — цвет не задан.
+	Color string
+	// TimeZone — часовой пояс IANA, например "Europe/Moscow".
+	TimeZone string
+	// IsDefault — календарь, в который попадают события без явно указанного календаря.
+	IsDefault bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+const calendarColumns = `id, owner_id, name, color, timezone, is_default, created_at, updated_at`
+
+func scanCalendar(row rowScanner) (Calendar, error) {
+	var c Calendar
+	err := row.Scan(&c.ID, &c.OwnerID, &c.Name, &c.Color, &c.TimeZone, &c.IsDefault, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+// CreateCalendar добавляет новый календарь и заполняет CreatedAt/UpdatedAt.
+func (s *PGEventStorage) CreateCalendar(ctx context.Context, c *Calendar) error {
+	const query = `
+		INSERT INTO calendars (id, owner_id, name, color, timezone)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, updated_at
+	`
+
+	return s.db.QueryRowContext(ctx, query, c.ID, c.OwnerID, c.Name, c.Color, c.TimeZone).
+		Scan(&c.CreatedAt, &c.UpdatedAt)
+}
+
+// GetCalendar возвращает календарь по ID или sql.ErrNoRows.
+func (s *PGEventStorage) GetCalendar(ctx context.Context, id string) (*Calendar, error) {
+	const query = `SELECT ` + calendarColumns + ` FROM calendars WHERE id = $1`
+
+	c, err := scanCalendar(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// EnsureDefaultCalendar возвращает календарь владельца по умолчанию, при необходимости создавая его.
+func (s *PGEventStorage) EnsureDefaultCalendar(ctx context.Context, ownerID string) (*Calendar, error) {
+	const insertQuery = `
+		INSERT INTO calendars (owner_id, name, is_default)
+		VALUES ($1, $2, TRUE)
+		ON CONFLICT (owner_id) WHERE is_default DO NOTHING
+	`
+
+	if _, err := s.db.ExecContext(ctx, insertQuery, ownerID, DefaultCalendarName); err != nil {
+		return nil, err
+	}
+	return s.GetDefaultCalendar(ctx, ownerID)
+}
+
+// GetDefaultCalendar возвращает календарь владельца по умолчанию; если его ещё нет — sql.ErrNoRows.
+func (s *PGEventStorage) GetDefaultCalendar(ctx context.Context, ownerID string) (*Calendar, error) {
+	const query = `SELECT ` + calendarColumns + ` FROM calendars WHERE owner_id = $1 AND is_default`
+
+	c, err := scanCalendar(s.db.QueryRowContext(ctx, query, ownerID))
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListCalendars возвращает календари владельца: сначала календарь по умолчанию, затем по названию.
+func (s *PGEventStorage) ListCalendars(ctx context.Context, ownerID string) ([]Calendar, error) {
+	const query = `
+		SELECT ` + calendarColumns + `
+		FROM calendars
+		WHERE owner_id = $1
+		ORDER BY is_default DESC, name, id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calendars []Calendar
+	for rows.Next() {
+		c, err := scanCalendar(rows)
+		if err != nil {
+			return nil, err
+		}
+		calendars = append(calendars, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+// CalendarFields — набор полей календаря, которые меняет UpdateCalendar. Поля из набора
+// записываются как есть, в том числе пустыми, остальные сохраняют прежние значения.
+type CalendarFields uint8
+
+const (
+	FieldCalendarName CalendarFields = 1 << iota
+	FieldCalendarColor
+	FieldCalendarTimeZone
+)
+
+// Has сообщает, что в наборе есть все поля f.
+func (fs CalendarFields) Has(f CalendarFields) bool {
+	return fs&f == f
+}
+
+// UpdateCalendar записывает поля fields календаря c.ID значениями из c; остальные поля
+// сохраняют прежние значения. c заполняется текущим состоянием календаря.
+func (s *PGEventStorage) UpdateCalendar(ctx context.Context, c *Calendar, fields CalendarFields) error {
+	args := []any{c.ID}
+	set := ""
+	assign := func(field CalendarFields, column string, value any) {
+		if fields.Has(field) {
+			args = append(args, value)
+			set += column + " = $" + strconv.Itoa(len(args)) + ", "
+		}
+	}
+	assign(FieldCalendarName, "name", c.Name)
+	assign(FieldCalendarColor, "color", c.Color)
+	assign(FieldCalendarTimeZone, "timezone", c.TimeZone)
+
+	query := `
+		UPDATE calendars
+		SET ` + set + `
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + calendarColumns
+
+	updated, err := scanCalendar(s.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return err
+	}
+	*c = updated
+	return nil
+}
+
+// DeleteCalendar удаляет календарь вместе с его событиями; об удалении каждого события
+// в outbox записывается уведомление. Календарь по умолчанию удалить нельзя (ErrDefaultCalendar).
+func (s *PGEventStorage) DeleteCalendar(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		var isDefault bool
+		const lockQuery = `SELECT is_default FROM calendars WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, lockQuery, id).Scan(&isDefault); err != nil {
+			return err
+		}
+		if isDefault {
+			return ErrDefaultCalendar
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteAndRecordQuery(`calendar_id = $1`, 2), id, traceContext(ctx)); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `DELETE FROM calendars WHERE id = $1`, id)
+		return err
+	})
+}
@@ -3,9 +3,17 @@ package repos
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strconv"
 	"time"
+
+	"github.com/lib/pq"
 )
 
+// ErrVersionMismatch — событие изменилось с тех пор, как клиент получил его версию.
+var ErrVersionMismatch = errors.New("event version mismatch")
+
 // Event описывает сущность события календаря.
 type Event struct {
 	ID          string
@@ -14,8 +22,230 @@ type Event struct {
 	StartTime   time.Time
 	EndTime     time.Time
 	OwnerID     string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// CalendarID — календарь владельца, в котором лежит событие.
+	// У переопределения вхождения совпадает с календарём серии.
+	CalendarID string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	// Version увеличивается при каждом изменении события.
+	Version int64
+	// SeriesVersion — версия серии переопределённого вхождения: от неё зависят, например,
+	// участники вхождения. Не читается из events: заполняется сервисом.
+	SeriesVersion int64
+
+	// RRule — правило повторения RFC 5545 без префикса "RRULE:" (например, "FREQ=WEEKLY;BYDAY=MO").
+	// Пустая строка означает разовое событие.
+	RRule   string
+	ExDates []time.Time // EXDATE — исключённые вхождения серии
+	RDates  []time.Time // RDATE — дополнительные вхождения серии
+	// TimeZone — часовой пояс IANA (например, "Europe/Berlin"), в котором разворачивается
+	// серия: от начала события в этом поясе считаются BYDAY и время вхождений.
+	TimeZone string
+
+	// RecurringEventID и RecurrenceID заполнены у переопределённого вхождения серии
+	// (аналог RECURRENCE-ID в RFC 5545) и у развёрнутых вхождений в выдаче ListEvents.
+	RecurringEventID string
+	RecurrenceID     time.Time
+
+	// Reminders — за сколько минут до начала (каждого вхождения) напомнить о событии.
+	Reminders []int
+
+	// ICalUID — UID события из импортированного iCalendar; пустой у событий, созданных через API.
+	// У переопределения вхождения совпадает с UID серии.
+	ICalUID string
+
+	// Attendees — приглашённые участники. Не читается из events: заполняется сервисом.
+	Attendees []Attendee
+
+	// Floating — время импортированного события задано без пояса («плавающее» или дата,
+	// RFC 5545, 3.3.5): StartTime, EndTime, RecurrenceID, ExDates и RDates хранят местное
+	// время в UTC, а в пояс календаря события их переносит сервис. Не хранится в events.
+	Floating bool
+}
+
+// EventFields — набор полей события, которые меняет UpdateEvent. Поля из набора записываются
+// как есть, в том числе пустыми, остальные сохраняют прежние значения.
+type EventFields uint16
+
+const (
+	FieldTitle EventFields = 1 << iota
+	FieldDescription
+	FieldStartTime
+	FieldEndTime
+	FieldOwnerID
+	FieldCalendarID
+	FieldRRule
+	FieldExDates
+	FieldRDates
+	FieldReminders
+	FieldTimeZone
+)
+
+const (
+	// RecurrenceFields — правило повторения серии и часовой пояс, в котором оно разворачивается.
+	RecurrenceFields = FieldRRule | FieldExDates | FieldRDates | FieldTimeZone
+	// ContentFields — всё содержимое события, кроме владельца и календаря.
+	ContentFields = FieldTitle | FieldDescription | FieldStartTime | FieldEndTime | RecurrenceFields | FieldReminders
+)
+
+// Has сообщает, что в наборе есть все поля f.
+func (fs EventFields) Has(f EventFields) bool {
+	return fs&f == f
+}
+
+// querier — общее подмножество *sql.DB и *sql.Tx, чтобы одни и те же запросы работали и в транзакции.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// eventColumns — колонки, которые читаются в Event функцией scanEvent.
+const eventColumns = `
+	id,
+	title,
+	description,
+	start_time,
+	end_time,
+	owner_id,
+	created_at,
+	updated_at,
+	version,
+	rrule,
+	exdates,
+	rdates,
+	recurring_event_id,
+	recurrence_id,
+	reminders,
+	ical_uid,
+	calendar_id,
+	timezone
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanEvent читает одну строку, выбранную с eventColumns.
+func scanEvent(row rowScanner) (Event, error) {
+	var (
+		e                Event
+		recurringEventID sql.NullString
+		recurrenceID     sql.NullTime
+		icalUID          sql.NullString
+	)
+	err := row.Scan(
+		&e.ID,
+		&e.Title,
+		&e.Description,
+		&e.StartTime,
+		&e.EndTime,
+		&e.OwnerID,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+		&e.Version,
+		&e.RRule,
+		(*timeList)(&e.ExDates),
+		(*timeList)(&e.RDates),
+		&recurringEventID,
+		&recurrenceID,
+		(*minutesList)(&e.Reminders),
+		&icalUID,
+		&e.CalendarID,
+		&e.TimeZone,
+	)
+	if err != nil {
+		return Event{}, err
+	}
+	e.RecurringEventID = recurringEventID.String
+	e.RecurrenceID = recurrenceID.Time
+	e.ICalUID = icalUID.String
+	return e, nil
+}
+
+// scanEvents читает все строки результата и закрывает rows.
+func scanEvents(rows *sql.Rows) ([]Event, error) {
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// minutesList хранит []int в колонке INTEGER[].
+type minutesList []int
+
+// Value реализует driver.Valuer.
+func (l minutesList) Value() (driver.Value, error) {
+	ints := make(pq.Int64Array, 0, len(l))
+	for _, m := range l {
+		ints = append(ints, int64(m))
+	}
+	return ints.Value()
+}
+
+// Scan реализует sql.Scanner.
+func (l *minutesList) Scan(src any) error {
+	var ints pq.Int64Array
+	if err := ints.Scan(src); err != nil {
+		return err
+	}
+
+	res := make(minutesList, 0, len(ints))
+	for _, m := range ints {
+		res = append(res, int(m))
+	}
+	*l = res
+	return nil
+}
+
+// timeList хранит []time.Time в колонке TIMESTAMPTZ[].
+type timeList []time.Time
+
+// Value реализует driver.Valuer.
+func (l timeList) Value() (driver.Value, error) {
+	strs := make(pq.StringArray, 0, len(l))
+	for _, t := range l {
+		strs = append(strs, t.UTC().Format(time.RFC3339Nano))
+	}
+	return strs.Value()
+}
+
+// Scan реализует sql.Scanner.
+func (l *timeList) Scan(src any) error {
+	var strs pq.StringArray
+	if err := strs.Scan(src); err != nil {
+		return err
+	}
+
+	res := make(timeList, 0, len(strs))
+	for _, s := range strs {
+		t, err := pq.ParseTimestamp(time.UTC, s)
+		if err != nil {
+			return err
+		}
+		res = append(res, t)
+	}
+	*l = res
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
 }
 
 // PGEventStorage — реализация хранилища событий поверх PostgreSQL (*sql.DB).
@@ -28,15 +258,33 @@ func NewPGEventStorage(db *sql.DB) *PGEventStorage {
 	return &PGEventStorage{db: db}
 }
 
-// CreateEvent добавляет новое событие и заполняет ID/CreatedAt/UpdatedAt.
-func (s *PGEventStorage) CreateEvent(ctx context.Context, e *Event) error {
+// CreateEvent добавляет новое событие и заполняет CreatedAt/UpdatedAt/Version.
+// Вместе с событием в outbox записывается уведомление о нём.
+// Если задан guard, событие добавляется, только если проверка прошла (см. Guard).
+func (s *PGEventStorage) CreateEvent(ctx context.Context, e *Event, guard *Guard) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkGuard(ctx, tx, guard); err != nil {
+			return err
+		}
+		if err := insertEvent(ctx, tx, e); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, ChangeCreated, e.ID, nil)
+	})
+}
+
+func insertEvent(ctx context.Context, q querier, e *Event) error {
 	const query = `
-		INSERT INTO events (id, title, description, start_time, end_time, owner_id)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING created_at, updated_at
+		INSERT INTO events (
+			id, title, description, start_time, end_time, owner_id,
+			rrule, exdates, rdates, recurring_event_id, recurrence_id, reminders, ical_uid, calendar_id,
+			timezone
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING created_at, updated_at, version
 	`
 
-	return s.db.QueryRowContext(
+	return q.QueryRowContext(
 		ctx,
 		query,
 		e.ID,
@@ -45,104 +293,318 @@ func (s *PGEventStorage) CreateEvent(ctx context.Context, e *Event) error {
 		e.StartTime,
 		e.EndTime,
 		e.OwnerID,
-	).Scan(&e.CreatedAt, &e.UpdatedAt)
+		e.RRule,
+		timeList(e.ExDates),
+		timeList(e.RDates),
+		nullString(e.RecurringEventID),
+		nullTime(e.RecurrenceID),
+		minutesList(e.Reminders),
+		nullString(e.ICalUID),
+		e.CalendarID,
+		e.TimeZone,
+	).Scan(&e.CreatedAt, &e.UpdatedAt, &e.Version)
 }
 
-// UpdateEvent изменяет существующее событие по ID.
-// Обновляет только те поля, которые были установлены (непустые для строк, не нулевые для времени).
-func (s *PGEventStorage) UpdateEvent(ctx context.Context, e *Event) error {
-	// Сначала получаем существующее событие
-	const selectQuery = `
-		SELECT title, description, start_time, end_time, owner_id
+// GetEvent возвращает событие по ID или sql.ErrNoRows.
+func (s *PGEventStorage) GetEvent(ctx context.Context, id string) (*Event, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE id = $1`
+
+	e, err := scanEvent(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// GetEventByUID возвращает событие или серию владельца ownerID с iCalendar UID uid или sql.ErrNoRows.
+// UID совпадает либо с ICalUID импортированного события, либо с ID события, выгруженного этим сервисом.
+func (s *PGEventStorage) GetEventByUID(ctx context.Context, ownerID, uid string) (*Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
 		FROM events
-		WHERE id = $1
+		WHERE owner_id = $1
+			AND recurring_event_id IS NULL
+			AND (ical_uid = $2 OR id::text = $2)
+		ORDER BY ical_uid = $2 DESC
+		LIMIT 1
 	`
 
-	var existing Event
-	err := s.db.QueryRowContext(ctx, selectQuery, e.ID).Scan(
-		&existing.Title,
-		&existing.Description,
-		&existing.StartTime,
-		&existing.EndTime,
-		&existing.OwnerID,
-	)
+	e, err := scanEvent(s.db.QueryRowContext(ctx, query, ownerID, uid))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return sql.ErrNoRows
-		}
-		return err
+		return nil, err
 	}
+	return &e, nil
+}
 
-	// Определяем, какие поля нужно обновить
-	// Используем существующие значения для полей, которые не были переданы
-	updateTitle := existing.Title
-	updateDescription := existing.Description
-	updateStartTime := existing.StartTime
-	updateEndTime := existing.EndTime
-	updateOwnerID := existing.OwnerID
+// ListEventsByUID возвращает событие или серию владельца ownerID с iCalendar UID uid
+// (см. GetEventByUID) вместе с переопределениями вхождений; серия идёт первой.
+func (s *PGEventStorage) ListEventsByUID(ctx context.Context, ownerID, uid string) ([]Event, error) {
+	query := `
+		WITH master AS (
+			SELECT id
+			FROM events
+			WHERE owner_id = $1
+				AND recurring_event_id IS NULL
+				AND (ical_uid = $2 OR id::text = $2)
+			ORDER BY ical_uid = $2 DESC
+			LIMIT 1
+		)
+		SELECT ` + eventColumns + `
+		FROM events
+		WHERE id IN (SELECT id FROM master)
+			OR recurring_event_id IN (SELECT id FROM master)
+		ORDER BY recurrence_id NULLS FIRST
+	`
 
-	// Обновляем только переданные поля (непустые/ненулевые)
-	if e.Title != "" {
-		updateTitle = e.Title
-	}
-	if e.Description != "" {
-		updateDescription = e.Description
-	}
-	if !e.StartTime.IsZero() {
-		updateStartTime = e.StartTime
+	rows, err := s.db.QueryContext(ctx, query, ownerID, uid)
+	if err != nil {
+		return nil, err
 	}
-	if !e.EndTime.IsZero() {
-		updateEndTime = e.EndTime
+	return scanEvents(rows)
+}
+
+// GetOverride возвращает переопределение вхождения серии seriesID, начинавшегося в recurrenceID,
+// или sql.ErrNoRows.
+func (s *PGEventStorage) GetOverride(ctx context.Context, seriesID string, recurrenceID time.Time) (*Event, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE recurring_event_id = $1 AND recurrence_id = $2`
+
+	e, err := scanEvent(s.db.QueryRowContext(ctx, query, seriesID, recurrenceID))
+	if err != nil {
+		return nil, err
 	}
-	if e.OwnerID != "" {
-		updateOwnerID = e.OwnerID
+	return &e, nil
+}
+
+// UpdateEvent изменяет поля fields события e.ID значениями из e; остальные поля сохраняют
+// прежние значения.
+// Если e.Version не 0, событие обновляется, только если его текущая версия равна e.Version,
+// иначе — ErrVersionMismatch. После обновления e.Version — новая версия события.
+// Если задан guard, событие обновляется, только если проверка прошла (см. Guard).
+func (s *PGEventStorage) UpdateEvent(ctx context.Context, e *Event, fields EventFields, guard *Guard) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkGuard(ctx, tx, guard); err != nil {
+			return err
+		}
+		before, err := lockSnapshot(ctx, tx, e.ID)
+		if err != nil {
+			return err
+		}
+		if err := updateEvent(ctx, tx, e, fields); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, ChangeUpdated, e.ID, before)
+	})
+}
+
+// updateEvent обновляет событие одним условным запросом: меняются только поля fields,
+// а версия сверяется с e.Version в том же запросе.
+func updateEvent(ctx context.Context, q querier, e *Event, fields EventFields) error {
+	args := []any{e.ID, e.Version}
+	set := ""
+	assign := func(field EventFields, column string, value any) {
+		if fields.Has(field) {
+			args = append(args, value)
+			set += column + " = $" + strconv.Itoa(len(args)) + ", "
+		}
 	}
+	assign(FieldTitle, "title", e.Title)
+	assign(FieldDescription, "description", e.Description)
+	assign(FieldStartTime, "start_time", e.StartTime)
+	assign(FieldEndTime, "end_time", e.EndTime)
+	assign(FieldOwnerID, "owner_id", e.OwnerID)
+	assign(FieldCalendarID, "calendar_id", e.CalendarID)
+	assign(FieldRRule, "rrule", e.RRule)
+	assign(FieldExDates, "exdates", timeList(e.ExDates))
+	assign(FieldRDates, "rdates", timeList(e.RDates))
+	assign(FieldReminders, "reminders", minutesList(e.Reminders))
+	assign(FieldTimeZone, "timezone", e.TimeZone)
 
-	const updateQuery = `
-		UPDATE events
-		SET
-			title       = $1,
-			description = $2,
-			start_time  = $3,
-			end_time    = $4,
-			owner_id    = $5,
-			updated_at  = NOW()
-		WHERE id = $6
+	// Время и правило серии могли измениться, поэтому следующее напоминание
+	// планировщик пересчитает заново (см. AdvanceReminders).
+	query := `
+		WITH old AS (
+			SELECT id, owner_id, calendar_id FROM events WHERE id = $1
+		)
+		UPDATE events e
+		SET ` + set + `
+			reminders_next_at = NULL,
+			version           = e.version + 1,
+			updated_at        = NOW()
+		FROM old
+		WHERE e.id = old.id AND ($2::bigint = 0 OR e.version = $2)
+		RETURNING e.version, e.owner_id, e.calendar_id, old.owner_id, old.calendar_id
 	`
 
-	res, err := s.db.ExecContext(
-		ctx,
-		updateQuery,
-		updateTitle,
-		updateDescription,
-		updateStartTime,
-		updateEndTime,
-		updateOwnerID,
-		e.ID,
+	var (
+		oldOwnerID    string
+		oldCalendarID string
 	)
-	if err != nil {
-		return err
+	err := q.QueryRowContext(ctx, query, args...).
+		Scan(&e.Version, &e.OwnerID, &e.CalendarID, &oldOwnerID, &oldCalendarID)
+	if errors.Is(err, sql.ErrNoRows) && e.Version != 0 {
+		// Строка события заблокирована и существует (lockSnapshot), значит, не совпала версия.
+		return ErrVersionMismatch
 	}
-
-	n, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
-	if n == 0 {
-		return sql.ErrNoRows
+
+	// Переопределения вхождений переезжают вместе с серией.
+	if e.OwnerID == oldOwnerID && e.CalendarID == oldCalendarID {
+		return nil
 	}
-	return nil
+
+	const overridesQuery = `
+		UPDATE events
+		SET
+			owner_id    = $1,
+			calendar_id = $2,
+			version     = version + 1,
+			updated_at  = NOW()
+		WHERE recurring_event_id = $3
+	`
+
+	_, err = q.ExecContext(ctx, overridesQuery, e.OwnerID, e.CalendarID, e.ID)
+	return err
 }
 
-// DeleteEvent удаляет событие по ID.
-func (s *PGEventStorage) DeleteEvent(ctx context.Context, id string) error {
-	const query = `DELETE FROM events WHERE id = $1`
+// ExcludeOccurrence исключает одно вхождение серии: добавляет его в EXDATE
+// и удаляет переопределение этого вхождения, если оно было.
+// Если version не 0, вхождение исключается, только если текущая версия серии равна version,
+// иначе — ErrVersionMismatch.
+func (s *PGEventStorage) ExcludeOccurrence(ctx context.Context, seriesID string, recurrenceID time.Time, version int64) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := lockSnapshot(ctx, tx, seriesID)
+		if err != nil {
+			return err
+		}
 
-	res, err := s.db.ExecContext(ctx, query, id)
-	if err != nil {
+		const excludeQuery = `
+			UPDATE events
+			SET
+				exdates    = array_append(exdates, $2),
+				version    = version + 1,
+				updated_at = NOW()
+			WHERE id = $1 AND ($3::bigint = 0 OR version = $3)
+		`
+
+		res, err := tx.ExecContext(ctx, excludeQuery, seriesID, recurrenceID, version)
+		if err != nil {
+			return err
+		}
+		if err := expectRows(res); errors.Is(err, sql.ErrNoRows) {
+			// Строка серии заблокирована и существует (lockSnapshot), значит, не совпала версия.
+			return ErrVersionMismatch
+		} else if err != nil {
+			return err
+		}
+		if err := recordChange(ctx, tx, ChangeUpdated, seriesID, before); err != nil {
+			return err
+		}
+
+		deleteOverrideQuery := deleteAndRecordQuery(`recurring_event_id = $1 AND recurrence_id = $2`, 3)
+
+		_, err = tx.ExecContext(ctx, deleteOverrideQuery, seriesID, recurrenceID, traceContext(ctx))
 		return err
+	})
+}
+
+// SplitSeries атомарно обрезает серию в момент at: сохраняет у series новые RRULE/EXDATE/RDATE,
+// удаляет переопределения вхождений начиная с at и, если next не nil, создаёт продолжение серии.
+// Если series.Version не 0, серия разрезается, только если её текущая версия равна series.Version,
+// иначе — ErrVersionMismatch.
+// Если задан guard, серия разрезается, только если проверка прошла (см. Guard).
+func (s *PGEventStorage) SplitSeries(ctx context.Context, series *Event, at time.Time, next *Event, guard *Guard) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkGuard(ctx, tx, guard); err != nil {
+			return err
+		}
+		before, err := lockSnapshot(ctx, tx, series.ID)
+		if err != nil {
+			return err
+		}
+
+		const truncateQuery = `
+			UPDATE events
+			SET
+				rrule      = $1,
+				exdates    = $2,
+				rdates     = $3,
+				version    = version + 1,
+				updated_at = NOW()
+			WHERE id = $4 AND ($5::bigint = 0 OR version = $5)
+		`
+
+		res, err := tx.ExecContext(
+			ctx,
+			truncateQuery,
+			series.RRule,
+			timeList(series.ExDates),
+			timeList(series.RDates),
+			series.ID,
+			series.Version,
+		)
+		if err != nil {
+			return err
+		}
+		if err := expectRows(res); errors.Is(err, sql.ErrNoRows) {
+			// Строка серии заблокирована и существует (lockSnapshot), значит, не совпала версия.
+			return ErrVersionMismatch
+		} else if err != nil {
+			return err
+		}
+		if err := recordChange(ctx, tx, ChangeUpdated, series.ID, before); err != nil {
+			return err
+		}
+
+		deleteOverridesQuery := deleteAndRecordQuery(`recurring_event_id = $1 AND recurrence_id >= $2`, 3)
+
+		if _, err := tx.ExecContext(ctx, deleteOverridesQuery, series.ID, at, traceContext(ctx)); err != nil {
+			return err
+		}
+
+		if next == nil {
+			return nil
+		}
+		if err := insertEvent(ctx, tx, next); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, ChangeCreated, next.ID, nil)
+	})
+}
+
+// DeleteEvent удаляет событие по ID вместе с переопределениями вхождений, если это серия.
+// Последний снимок каждой удалённой строки записывается в outbox.
+// Если version не 0, событие удаляется, только если его текущая версия равна version,
+// иначе — ErrVersionMismatch.
+func (s *PGEventStorage) DeleteEvent(ctx context.Context, id string, version int64) error {
+	query := deleteAndRecordQuery(`id = $1 OR recurring_event_id = $1`, 2)
+
+	if version == 0 {
+		res, err := s.db.ExecContext(ctx, query, id, traceContext(ctx))
+		if err != nil {
+			return err
+		}
+		return expectRows(res)
 	}
 
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		var current int64
+		const lockQuery = `SELECT version FROM events WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, lockQuery, id).Scan(&current); err != nil {
+			return err
+		}
+		if current != version {
+			return ErrVersionMismatch
+		}
+
+		_, err := tx.ExecContext(ctx, query, id, traceContext(ctx))
+		return err
+	})
+}
+
+// expectRows возвращает sql.ErrNoRows, если запрос не затронул ни одной строки.
+func expectRows(res sql.Result) error {
 	n, err := res.RowsAffected()
 	if err != nil {
 		return err
@@ -153,95 +615,117 @@ func (s *PGEventStorage) DeleteEvent(ctx context.Context, id string) error {
 	return nil
 }
 
-// ListEvents возвращает все события конкретного владельца.
-func (s *PGEventStorage) ListEvents(ctx context.Context, ownerID string) ([]Event, error) {
-	const query = `
-		SELECT
-			id,
-			title,
-			description,
-			start_time,
-			end_time,
-			owner_id,
-			created_at,
-			updated_at
-		FROM events
-		WHERE owner_id = $1
-		ORDER BY start_time
-	`
+// Cursor — позиция в выдаче событий, упорядоченной по (start_time, id).
+type Cursor struct {
+	StartTime time.Time
+	ID        string
+}
 
-	rows, err := s.db.QueryContext(ctx, query, ownerID)
-	if err != nil {
-		return nil, err
+// cursorArgs превращает курсор в параметры условия keyset-пагинации;
+// для первой страницы оба параметра NULL.
+func cursorArgs(after *Cursor) (sql.NullTime, sql.NullString) {
+	if after == nil {
+		return sql.NullTime{}, sql.NullString{}
 	}
-	defer rows.Close()
+	return nullTime(after.StartTime), nullString(after.ID)
+}
 
-	var events []Event
-	for rows.Next() {
-		var e Event
-		if err := rows.Scan(
-			&e.ID,
-			&e.Title,
-			&e.Description,
-			&e.StartTime,
-			&e.EndTime,
-			&e.OwnerID,
-			&e.CreatedAt,
-			&e.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		events = append(events, e)
-	}
+// invitedEventIDs — подзапрос ID событий, на которые приглашён пользователь $1.
+const invitedEventIDs = `SELECT event_id FROM event_attendees WHERE user_id = $1`
 
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return events, nil
-}
+// inWindow — условие на строку e таблицы events: её вхождения могут пересекаться с окном [$2, $3).
+// Это разовые события и переопределения, пересекающиеся с окном, серии, начавшиеся до $3,
+// а также переопределения, исходное вхождение которых попадает в окно (без них вхождение
+// серии попало бы в окно по исходному времени).
+const inWindow = `(
+	(e.rrule = '' AND e.start_time < $3 AND (e.end_time > $2 OR e.start_time >= $2))
+	OR (e.rrule <> '' AND e.start_time < $3)
+	OR (
+		e.recurring_event_id IS NOT NULL
+		AND e.recurrence_id < $3
+		AND e.recurrence_id + (SELECT s.end_time - s.start_time FROM events s WHERE s.id = e.recurring_event_id) >= $2
+	)
+)`
 
-// GetAllEvents возвращает все события из БД.
-func (s *PGEventStorage) GetAllEvents(ctx context.Context) ([]Event, error) {
-	const query = `
-		SELECT
-			id,
-			title,
-			description,
-			start_time,
-			end_time,
-			owner_id,
-			created_at,
-			updated_at
+// ListEvents возвращает не больше limit событий пользователя, идущих после курсора after
+// (nil — с начала) в порядке (start_time, id). События пользователя — те, которыми он владеет,
+// и те, на которые он приглашён (включая переопределения вхождений таких серий).
+// Если calendarIDs не nil, выбираются только события из этих календарей.
+func (s *PGEventStorage) ListEvents(ctx context.Context, ownerID string, calendarIDs []string, after *Cursor, limit int) ([]Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
 		FROM events
-		ORDER BY start_time
+		WHERE (
+				owner_id = $1
+				OR id IN (` + invitedEventIDs + `)
+				OR recurring_event_id IN (` + invitedEventIDs + `)
+			)
+			AND ($2::timestamptz IS NULL OR (start_time >= $2 AND (start_time, id) > ($2, $3::uuid)))
+			AND ($5::uuid[] IS NULL OR calendar_id = ANY($5))
+		ORDER BY start_time, id
+		LIMIT $4
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+	afterStart, afterID := cursorArgs(after)
+	rows, err := s.db.QueryContext(ctx, query, ownerID, afterStart, afterID, limit, pq.Array(calendarIDs))
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanEvents(rows)
+}
 
-	var events []Event
-	for rows.Next() {
-		var e Event
-		if err := rows.Scan(
-			&e.ID,
-			&e.Title,
-			&e.Description,
-			&e.StartTime,
-			&e.EndTime,
-			&e.OwnerID,
-			&e.CreatedAt,
-			&e.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		events = append(events, e)
-	}
+// ListEventsInRange возвращает события пользователя (см. ListEvents), которые могут пересекаться с окном [from, to):
+// не больше limit разовых событий после курсора after, пересекающихся с окном, а также
+// без ограничения — все серии, начавшиеся до to (их вхождения разворачивает и отсекает
+// по курсору сервис), и переопределения вхождений этих серий, исходное время которых
+// попадает в окно, даже если само переопределение перенесено за его пределы.
+// Основная часть условия идёт по индексу idx_events_owner_start_time.
+// Если calendarIDs не nil, выбираются только события из этих календарей.
+func (s *PGEventStorage) ListEventsInRange(ctx context.Context, ownerID string, calendarIDs []string, from, to time.Time, after *Cursor, limit int) ([]Event, error) {
+	query := `
+		(
+			SELECT ` + eventColumns + `
+			FROM events
+			WHERE (
+					owner_id = $1
+					OR id IN (` + invitedEventIDs + `)
+					OR recurring_event_id IN (` + invitedEventIDs + `)
+				)
+				AND rrule = ''
+				AND start_time < $3
+				AND (end_time > $2 OR start_time >= $2)
+				AND ($4::timestamptz IS NULL OR (start_time >= $4 AND (start_time, id) > ($4, $5::uuid)))
+				AND ($7::uuid[] IS NULL OR calendar_id = ANY($7))
+			ORDER BY start_time, id
+			LIMIT $6
+		)
+		UNION
+		SELECT ` + eventColumns + `
+		FROM events
+		WHERE (
+				(
+					(owner_id = $1 OR id IN (` + invitedEventIDs + `))
+					AND rrule <> ''
+					AND start_time < $3
+				)
+				OR id IN (
+					SELECT o.id
+					FROM events o
+					JOIN events s ON s.id = o.recurring_event_id
+					WHERE (s.owner_id = $1 OR s.id IN (` + invitedEventIDs + `))
+						AND s.start_time < $3
+						AND o.recurrence_id < $3
+						AND o.recurrence_id + (s.end_time - s.start_time) >= $2
+				)
+			)
+			AND ($7::uuid[] IS NULL OR calendar_id = ANY($7))
+		ORDER BY start_time, id
+	`
 
-	if err := rows.Err(); err != nil {
+	afterStart, afterID := cursorArgs(after)
+	rows, err := s.db.QueryContext(ctx, query, ownerID, from, to, afterStart, afterID, limit, pq.Array(calendarIDs))
+	if err != nil {
 		return nil, err
 	}
-	return events, nil
+	return scanEvents(rows)
 }
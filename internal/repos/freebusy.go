@@ -0,0 +1,89 @@
+package repos
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ListBusyEvents возвращает для каждого пользователя из userIDs события, которые могут занимать
+// его время в окне [from, to): его собственные и те, на которые он приглашён и не отказался.
+// Серии вместе с переопределениями вхождений возвращаются без разворачивания, как в ListEventsInRange.
+// Если для пользователя есть запись в calendarIDs, берутся только его события из этих календарей,
+// а приглашения не учитываются: они не относятся ни к одному из его календарей.
+// Выборка по всем пользователям делается одним запросом.
+func (s *PGEventStorage) ListBusyEvents(ctx context.Context, userIDs []string, calendarIDs map[string][]string, from, to time.Time) (map[string][]Event, error) {
+	res := make(map[string][]Event)
+	if len(userIDs) == 0 {
+		return res, nil
+	}
+
+	// Фильтр по календарям передаётся списком пользователей с фильтром и парами (пользователь, календарь).
+	filtered := []string{}
+	var filterUsers, filterCalendars []string
+	for userID, ids := range calendarIDs {
+		filtered = append(filtered, userID)
+		for _, id := range ids {
+			filterUsers = append(filterUsers, userID)
+			filterCalendars = append(filterCalendars, id)
+		}
+	}
+
+	query := `
+		WITH busy AS (
+			SELECT e.owner_id AS user_id, e.id AS event_id
+			FROM events e
+			WHERE e.owner_id = ANY($1) AND ` + inWindow + `
+				AND (
+					NOT e.owner_id = ANY($4)
+					OR (e.owner_id, e.calendar_id) IN (SELECT * FROM unnest($5::text[], $6::uuid[]))
+				)
+			UNION
+			SELECT a.user_id, e.id
+			FROM event_attendees a
+			JOIN events e ON e.id = a.event_id
+			WHERE a.user_id = ANY($1) AND NOT a.user_id = ANY($4) AND a.status <> 'declined' AND ` + inWindow + `
+			UNION
+			SELECT a.user_id, e.id
+			FROM event_attendees a
+			JOIN events e ON e.recurring_event_id = a.event_id
+			WHERE a.user_id = ANY($1) AND NOT a.user_id = ANY($4) AND a.status <> 'declined' AND ` + inWindow + `
+		)
+		SELECT busy.user_id, ` + eventColumns + `
+		FROM busy
+		JOIN events ON events.id = busy.event_id
+		ORDER BY busy.user_id, start_time, id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query,
+		pq.Array(userIDs), from, to, pq.Array(filtered), pq.Array(filterUsers), pq.Array(filterCalendars))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		e, err := scanEvent(withLeading{rows, &userID})
+		if err != nil {
+			return nil, err
+		}
+		res[userID] = append(res[userID], e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// withLeading читает первую колонку строки в dest, а остальные передаёт вызывающему Scan.
+type withLeading struct {
+	row  rowScanner
+	dest any
+}
+
+func (w withLeading) Scan(dest ...any) error {
+	return w.row.Scan(append([]any{w.dest}, dest...)...)
+}
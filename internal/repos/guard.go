@@ -0,0 +1,59 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Guard — проверка, которую CreateEvent, UpdateEvent и SplitSeries выполняют в своей
+// транзакции перед записью. Транзакции с Guard одного владельца идут по очереди
+// (advisory-блокировка на OwnerID), поэтому между проверкой и записью никто из них
+// не добавит событие, которое проверка должна была увидеть. Чтобы этого не сделали и записи
+// без проверки, они передают Guard без Check — только с блокировкой владельца.
+// Ошибка Check отменяет запись и возвращается вызывающему как есть.
+type Guard struct {
+	OwnerID string
+	// From и To — окно [From, To): Check получает события владельца, которые могут с ним
+	// пересекаться, в том же составе, что и ListEventsInRange (серии и переопределения
+	// вхождений — целиком), но без событий, на которые владелец только приглашён.
+	From time.Time
+	To   time.Time
+	// Check — nil, если нужна только блокировка.
+	Check func(existing []Event) error
+}
+
+// checkGuard берёт блокировку владельца и выполняет проверку g; nil — ни блокировки, ни проверки.
+// Вызывается до блокировки строк события, чтобы все транзакции с проверками
+// захватывали блокировки в одном порядке.
+func checkGuard(ctx context.Context, tx *sql.Tx, g *Guard) error {
+	if g == nil {
+		return nil
+	}
+
+	const lockQuery = `SELECT pg_advisory_xact_lock(hashtextextended('events:' || $1, 0))`
+
+	if _, err := tx.ExecContext(ctx, lockQuery, g.OwnerID); err != nil {
+		return err
+	}
+	if g.Check == nil {
+		return nil
+	}
+
+	query := `
+		SELECT ` + eventColumns + `
+		FROM events e
+		WHERE e.owner_id = $1 AND ` + inWindow + `
+		ORDER BY start_time, id
+	`
+
+	rows, err := tx.QueryContext(ctx, query, g.OwnerID, g.From, g.To)
+	if err != nil {
+		return err
+	}
+	existing, err := scanEvents(rows)
+	if err != nil {
+		return err
+	}
+	return g.Check(existing)
+}
@@ -0,0 +1,208 @@
+package repos
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// ChangeType — вид изменения события, записанного в outbox.
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// OutboxMessage — запись transactional outbox: уведомление об изменении события,
+// сохранённое в той же транзакции, что и само изменение.
+// Before и After — JSON-снимки строки events (колонки — ключи) до и после изменения;
+// у созданного события нет Before, у удалённого — After.
+type OutboxMessage struct {
+	ID         int64
+	EventID    string
+	Type       ChangeType
+	Version    int64
+	Before     []byte
+	After      []byte
+	OccurredAt time.Time
+	// TraceContext — JSON-объект с заголовками W3C Trace Context запроса, изменившего событие;
+	// nil, если запрос не трассировался.
+	TraceContext []byte
+}
+
+// eventSnapshot — SQL-выражение JSON-снимка строки events с алиасом e:
+// все колонки события плюс список его участников.
+const eventSnapshot = `
+	to_jsonb(e) || jsonb_build_object('attendees', COALESCE((
+		SELECT jsonb_agg(jsonb_build_object('user_id', a.user_id, 'status', a.status) ORDER BY a.user_id)
+		FROM event_attendees a
+		WHERE a.event_id = e.id
+	), '[]'::jsonb))
+`
+
+// lockSnapshot блокирует строку события до конца транзакции и возвращает её JSON-снимок
+// (Before для последующего recordChange) или sql.ErrNoRows.
+func lockSnapshot(ctx context.Context, q querier, eventID string) ([]byte, error) {
+	query := `SELECT ` + eventSnapshot + ` FROM events e WHERE id = $1 FOR UPDATE OF e`
+
+	var before []byte
+	if err := q.QueryRowContext(ctx, query, eventID).Scan(&before); err != nil {
+		return nil, err
+	}
+	return before, nil
+}
+
+// recordChange кладёт в outbox изменение события eventID с его текущим снимком в качестве After.
+// Вызывается внутри транзакции изменения, после него.
+func recordChange(ctx context.Context, q querier, changeType ChangeType, eventID string, before []byte) error {
+	query := `
+		INSERT INTO event_outbox (event_id, type, version, before, after, trace_context)
+		SELECT id, $2, version, $3::jsonb, ` + eventSnapshot + `, $4::jsonb
+		FROM events e
+		WHERE id = $1
+	`
+
+	// []byte драйвер передал бы как bytea, поэтому снимок уходит строкой.
+	_, err := q.ExecContext(ctx, query, eventID, changeType, nullString(string(before)), traceContext(ctx))
+	return err
+}
+
+// deleteAndRecordQuery строит запрос, который удаляет события e, подходящие под условие where,
+// и записывает каждое удалённое событие в outbox как изменение ChangeDeleted.
+// traceParam — номер параметра запроса с контекстом трассировки (см. traceContext).
+func deleteAndRecordQuery(where string, traceParam int) string {
+	return `
+		WITH deleted AS (
+			DELETE FROM events e
+			WHERE ` + where + `
+			RETURNING e.id, e.version, ` + eventSnapshot + ` AS before
+		)
+		INSERT INTO event_outbox (event_id, type, version, before, trace_context)
+		SELECT id, '` + string(ChangeDeleted) + `', version, before, $` + strconv.Itoa(traceParam) + `::jsonb FROM deleted
+	`
+}
+
+// traceContext возвращает контекст трассировки ctx в виде JSON для колонки trace_context outbox
+// или NULL, если запрос не трассируется.
+func traceContext(ctx context.Context) sql.NullString {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return sql.NullString{}
+	}
+
+	data, err := json.Marshal(carrier)
+	if err != nil {
+		return sql.NullString{}
+	}
+	return nullString(string(data))
+}
+
+// ClaimOutbox закрепляет за вызывающим до limit неотправленных записей outbox на время ttl
+// и возвращает их по порядку. Захват — короткая транзакция: запись в Kafka идёт уже после неё,
+// а результат отмечается MarkOutboxSent и ReleaseOutbox.
+// Несколько экземпляров сервиса забирают записи по очереди (advisory-блокировка на время захвата),
+// и запись события не забирается, пока более ранняя запись того же события закреплена за
+// другим экземпляром: иначе изменения события могли бы уйти в Kafka не по порядку.
+// Записи с истёкшим захватом (экземпляр упал, не успев их отметить) забираются снова.
+func (s *PGEventStorage) ClaimOutbox(ctx context.Context, limit int, ttl time.Duration) ([]OutboxMessage, error) {
+	var messages []OutboxMessage
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended('outbox', 0))`); err != nil {
+			return err
+		}
+
+		const query = `
+			WITH claimable AS (
+				SELECT o.id
+				FROM event_outbox o
+				WHERE o.sent_at IS NULL
+					AND (o.claimed_until IS NULL OR o.claimed_until < NOW())
+					AND NOT EXISTS (
+						SELECT 1
+						FROM event_outbox p
+						WHERE p.event_id = o.event_id
+							AND p.sent_at IS NULL
+							AND p.id < o.id
+							AND p.claimed_until >= NOW()
+					)
+				ORDER BY o.id
+				LIMIT $1
+			)
+			UPDATE event_outbox o
+			SET claimed_until = NOW() + make_interval(secs => $2)
+			FROM claimable c
+			WHERE o.id = c.id
+			RETURNING o.id, o.event_id, o.type, o.version, o.before, o.after, o.created_at, o.trace_context
+		`
+
+		rows, err := tx.QueryContext(ctx, query, limit, ttl.Seconds())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var m OutboxMessage
+			if err := rows.Scan(&m.ID, &m.EventID, &m.Type, &m.Version, &m.Before, &m.After, &m.OccurredAt, &m.TraceContext); err != nil {
+				return err
+			}
+			messages = append(messages, m)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING не сохраняет порядок выборки.
+	slices.SortFunc(messages, func(a, b OutboxMessage) int { return cmp.Compare(a.ID, b.ID) })
+	return messages, nil
+}
+
+// MarkOutboxSent помечает записи outbox ids доставленными.
+func (s *PGEventStorage) MarkOutboxSent(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	const query = `UPDATE event_outbox SET sent_at = NOW() WHERE id = ANY($1)`
+	_, err := s.db.ExecContext(ctx, query, pq.Array(ids))
+	return err
+}
+
+// ReleaseOutbox снимает захват с недоставленных записей outbox ids, чтобы их можно было
+// забрать снова, не дожидаясь истечения захвата.
+func (s *PGEventStorage) ReleaseOutbox(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	const query = `UPDATE event_outbox SET claimed_until = NULL WHERE id = ANY($1) AND sent_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, pq.Array(ids))
+	return err
+}
+
+// inTx выполняет fn в транзакции и фиксирует её, если fn не вернула ошибку.
+func (s *PGEventStorage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
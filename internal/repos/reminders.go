@@ -0,0 +1,229 @@
+package repos
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Reminder — напоминание о конкретном вхождении события, которое пора отправить.
+// Уникально по (EventID, OccurrenceStart, MinutesBefore), поэтому повторное планирование
+// того же напоминания (например, после перезапуска) ничего не меняет.
+type Reminder struct {
+	ID              int64
+	EventID         string
+	OccurrenceStart time.Time
+	MinutesBefore   int
+	FireAt          time.Time
+
+	// Заполняются при пересылке из текущего состояния события.
+	OwnerID       string
+	Title         string
+	OccurrenceEnd time.Time
+}
+
+// NextReminder — момент, не раньше которого серию EventID версии Version снова нужно
+// развернуть, чтобы запланировать её напоминания.
+type NextReminder struct {
+	EventID string
+	Version int64
+	// At — nil, если напоминаний у серии больше не будет.
+	At *time.Time
+}
+
+// ListEventsWithReminders возвращает события с напоминаниями, вхождения которых могут
+// начинаться в окне [from, to): разовые события, начинающиеся в окне, серии, начавшиеся
+// до to, ближайшее напоминание которых наступило к now (или ещё не вычислено), и
+// переопределения вхождений этих серий из окна (нужны, чтобы не напомнить о перенесённом
+// вхождении по исходному времени). Бесконечные серии без наступивших напоминаний не читаются.
+func (s *PGEventStorage) ListEventsWithReminders(ctx context.Context, from, to, now time.Time) ([]Event, error) {
+	query := `
+		WITH due_series AS (
+			SELECT id
+			FROM events
+			WHERE rrule <> ''
+				AND cardinality(reminders) > 0
+				AND start_time < $2
+				AND (reminders_next_at IS NULL OR reminders_next_at <= $3)
+		)
+		SELECT ` + eventColumns + `
+		FROM events
+		WHERE (
+				cardinality(reminders) > 0
+				AND rrule = ''
+				AND start_time >= $1
+				AND start_time < $2
+			)
+			OR id IN (SELECT id FROM due_series)
+			OR id IN (
+				SELECT o.id
+				FROM events o
+				JOIN due_series s ON s.id = o.recurring_event_id
+				WHERE o.recurrence_id >= $1
+					AND o.recurrence_id < $2
+			)
+		ORDER BY start_time
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, from, to, now)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+// AdvanceReminders сохраняет для серий момент их следующего напоминания. Серия, изменённая
+// после чтения (версия не совпала), не трогается: её следующее напоминание уже сброшено
+// изменением или осталось прежним и будет пересчитано на следующем такте.
+func (s *PGEventStorage) AdvanceReminders(ctx context.Context, next []NextReminder) error {
+	if len(next) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const query = `
+		UPDATE events
+		SET reminders_next_at = COALESCE($3::timestamptz, 'infinity')
+		WHERE id = $1 AND version = $2
+	`
+
+	for _, n := range next {
+		if _, err := tx.ExecContext(ctx, query, n.EventID, n.Version, n.At); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ScheduleReminders сохраняет напоминания к отправке и возвращает, сколько из них новые.
+// Уже запланированные ранее напоминания пропускаются.
+func (s *PGEventStorage) ScheduleReminders(ctx context.Context, reminders []Reminder) (int, error) {
+	if len(reminders) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	const query = `
+		INSERT INTO reminder_deliveries (event_id, occurrence_start, minutes_before, fire_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id, occurrence_start, minutes_before) DO NOTHING
+	`
+
+	scheduled := 0
+	for _, r := range reminders {
+		res, err := tx.ExecContext(ctx, query, r.EventID, r.OccurrenceStart, r.MinutesBefore, r.FireAt)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		scheduled += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return scheduled, nil
+}
+
+// ClaimReminders закрепляет за вызывающим до limit запланированных, но не отправленных
+// напоминаний на время ttl и возвращает их. Захват — один короткий запрос: запись в Kafka
+// идёт уже после него, а результат отмечается MarkRemindersSent и ReleaseReminders.
+// Напоминания, закреплённые за другим экземпляром, пропускаются, а с истёкшим захватом
+// (экземпляр упал, не успев их отметить) забираются снова.
+func (s *PGEventStorage) ClaimReminders(ctx context.Context, limit int, ttl time.Duration) ([]Reminder, error) {
+	const query = `
+		WITH claimable AS (
+			SELECT id
+			FROM reminder_deliveries
+			WHERE sent_at IS NULL
+				AND (claimed_until IS NULL OR claimed_until < NOW())
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE reminder_deliveries d
+		SET claimed_until = NOW() + make_interval(secs => $2)
+		FROM claimable c, events e
+		WHERE d.id = c.id
+			AND e.id = d.event_id
+		RETURNING
+			d.id,
+			d.event_id,
+			d.occurrence_start,
+			d.minutes_before,
+			d.fire_at,
+			e.owner_id,
+			e.title,
+			d.occurrence_start + (e.end_time - e.start_time)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, limit, ttl.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []Reminder
+	for rows.Next() {
+		var r Reminder
+		if err := rows.Scan(
+			&r.ID,
+			&r.EventID,
+			&r.OccurrenceStart,
+			&r.MinutesBefore,
+			&r.FireAt,
+			&r.OwnerID,
+			&r.Title,
+			&r.OccurrenceEnd,
+		); err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(reminders, func(a, b Reminder) int { return cmp.Compare(a.ID, b.ID) })
+	return reminders, nil
+}
+
+// MarkRemindersSent помечает напоминания ids отправленными.
+func (s *PGEventStorage) MarkRemindersSent(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	const query = `UPDATE reminder_deliveries SET sent_at = NOW() WHERE id = ANY($1)`
+	_, err := s.db.ExecContext(ctx, query, pq.Array(ids))
+	return err
+}
+
+// ReleaseReminders снимает захват с неотправленных напоминаний ids, чтобы их можно было
+// забрать снова, не дожидаясь истечения захвата.
+func (s *PGEventStorage) ReleaseReminders(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	const query = `UPDATE reminder_deliveries SET claimed_until = NULL WHERE id = ANY($1) AND sent_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, pq.Array(ids))
+	return err
+}
@@ -0,0 +1,118 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Share — доступ пользователя GranteeID к календарю CalendarID владельца OwnerID
+// (пустой CalendarID — ко всем его календарям) с ролью Role.
+type Share struct {
+	ID         string
+	OwnerID    string
+	CalendarID string
+	GranteeID  string
+	Role       string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+const shareColumns = `id, owner_id, calendar_id, grantee_id, role, created_at, updated_at`
+
+func scanShare(row rowScanner) (Share, error) {
+	var (
+		sh         Share
+		calendarID sql.NullString
+	)
+	err := row.Scan(&sh.ID, &sh.OwnerID, &calendarID, &sh.GranteeID, &sh.Role, &sh.CreatedAt, &sh.UpdatedAt)
+	if err != nil {
+		return Share{}, err
+	}
+	sh.CalendarID = calendarID.String
+	return sh, nil
+}
+
+// SaveShare выдаёт доступ или, если доступ к тому же календарю (или ко всем календарям владельца)
+// у пользователя уже есть, меняет его роль. Заполняет ID, CreatedAt и UpdatedAt.
+func (s *PGEventStorage) SaveShare(ctx context.Context, sh *Share) error {
+	// Для выдачи на все календари и на один календарь действуют разные уникальные индексы.
+	conflict := `(calendar_id, grantee_id) WHERE calendar_id IS NOT NULL`
+	if sh.CalendarID == "" {
+		conflict = `(owner_id, grantee_id) WHERE calendar_id IS NULL`
+	}
+
+	query := `
+		INSERT INTO calendar_shares (id, owner_id, calendar_id, grantee_id, role)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ` + conflict + `
+		DO UPDATE SET role = EXCLUDED.role, updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`
+
+	return s.db.QueryRowContext(ctx, query, sh.ID, sh.OwnerID, nullString(sh.CalendarID), sh.GranteeID, sh.Role).
+		Scan(&sh.ID, &sh.CreatedAt, &sh.UpdatedAt)
+}
+
+// GetShare возвращает выданный доступ по ID или sql.ErrNoRows.
+func (s *PGEventStorage) GetShare(ctx context.Context, id string) (*Share, error) {
+	const query = `SELECT ` + shareColumns + ` FROM calendar_shares WHERE id = $1`
+
+	sh, err := scanShare(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	return &sh, nil
+}
+
+// ListShares возвращает все доступы к календарям владельца.
+func (s *PGEventStorage) ListShares(ctx context.Context, ownerID string) ([]Share, error) {
+	const query = `
+		SELECT ` + shareColumns + `
+		FROM calendar_shares
+		WHERE owner_id = $1
+		ORDER BY grantee_id, calendar_id NULLS FIRST
+	`
+	return s.queryShares(ctx, query, ownerID)
+}
+
+// ListGrants возвращает доступы пользователя granteeID к календарям владельца ownerID.
+func (s *PGEventStorage) ListGrants(ctx context.Context, ownerID, granteeID string) ([]Share, error) {
+	const query = `
+		SELECT ` + shareColumns + `
+		FROM calendar_shares
+		WHERE grantee_id = $2 AND owner_id = $1
+	`
+	return s.queryShares(ctx, query, ownerID, granteeID)
+}
+
+// DeleteShare отзывает доступ по ID; если его нет — sql.ErrNoRows.
+func (s *PGEventStorage) DeleteShare(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM calendar_shares WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRows(res)
+}
+
+func (s *PGEventStorage) queryShares(ctx context.Context, query string, args ...any) ([]Share, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []Share
+	for rows.Next() {
+		sh, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, sh)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
@@ -0,0 +1,113 @@
+// Package requestlog присваивает запросам ID и пишет по каждому запросу запись в лог.
+package requestlog
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"calendar/internal/logger"
+)
+
+// Header — заголовок с ID запроса: принимается от клиента или прокси и возвращается в ответе.
+const Header = "X-Request-ID"
+
+// validID ограничивает принятые от клиента ID, чтобы в лог не попадали произвольные строки.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// quietPaths — служебные маршруты, которые опрашиваются постоянно; успешные запросы к ним
+// пишутся только на уровне debug.
+var quietPaths = map[string]bool{
+	"/livez":   true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+type idKey struct{}
+
+// IDFrom возвращает ID запроса из ctx.
+func IDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(idKey{}).(string)
+	return id, ok
+}
+
+// Middleware берёт ID запроса из X-Request-ID (или создаёт новый), возвращает его в ответе
+// и кладёт в контекст логгер запроса с request_id (и trace_id, если запрос трассируется).
+// После ответа пишет запись с методом, путём, кодом ответа, длительностью и пользователем,
+// которого добавила аутентификация. Middleware стоит перед аутентификацией, чтобы в лог
+// попадали и отклонённые ею запросы.
+func Middleware(log logger.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !validID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(Header, id)
+
+		reqLog := log.With("request_id", id)
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			reqLog = reqLog.With("trace_id", sc.TraceID().String())
+		}
+		ctx := context.WithValue(r.Context(), idKey{}, id)
+		ctx = logger.NewContext(ctx, reqLog)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		logger.FromContext(ctx).Log(ctx, slogLevel(r.URL.Path, rec.status), "http request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"bytes", rec.bytes,
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
+
+// statusRecorder запоминает код ответа и размер тела.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap даёт http.ResponseController добраться до исходного ResponseWriter.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// slogLevel — уровень записи о запросе: ошибки сервера — Error, успешные запросы
+// к служебным маршрутам — Debug, остальное — Info.
+func slogLevel(path string, status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case quietPaths[path] && status < http.StatusBadRequest:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"calendar/internal/auth"
+	"calendar/internal/logger"
+	"calendar/internal/repos"
+)
+
+var (
+	// ErrUnauthenticated — запрос выполняется без пользователя.
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrForbidden — у пользователя запроса нет нужного доступа.
+	ErrForbidden = errors.New("access denied")
+	// ErrInvalidShare — доступ задан некорректно.
+	ErrInvalidShare = errors.New("invalid share")
+)
+
+// Role — уровень доступа к календарю; каждая следующая роль включает предыдущие.
+type Role string
+
+const (
+	// RoleFreeBusy — только занятость (FreeBusy, SuggestTimes) без подробностей событий.
+	RoleFreeBusy Role = "free-busy"
+	// RoleReader — чтение событий.
+	RoleReader Role = "reader"
+	// RoleWriter — создание, изменение и удаление событий и их участников.
+	RoleWriter Role = "writer"
+	// RoleOwner — всё, включая изменение календаря и выдачу доступа к нему.
+	RoleOwner Role = "owner"
+)
+
+var roleRank = map[Role]int{
+	RoleFreeBusy: 1,
+	RoleReader:   2,
+	RoleWriter:   3,
+	RoleOwner:    4,
+}
+
+// ParseRole разбирает роль из запроса.
+func ParseRole(s string) (Role, error) {
+	if _, ok := roleRank[Role(s)]; !ok {
+		return "", fmt.Errorf("%w: unknown role %q", ErrInvalidShare, s)
+	}
+	return Role(s), nil
+}
+
+// includes сообщает, что роль r даёт всё, что даёт min. Пустая роль (доступа нет) не даёт ничего.
+func (r Role) includes(min Role) bool {
+	return roleRank[r] >= roleRank[min]
+}
+
+// SharesRepo — хранилище выданных доступов к календарям.
+type SharesRepo interface {
+	SaveShare(ctx context.Context, sh *repos.Share) error
+	GetShare(ctx context.Context, id string) (*repos.Share, error)
+	ListShares(ctx context.Context, ownerID string) ([]repos.Share, error)
+	ListGrants(ctx context.Context, ownerID, granteeID string) ([]repos.Share, error)
+	DeleteShare(ctx context.Context, id string) error
+}
+
+// access — права пользователя запроса на календари одного владельца.
+type access struct {
+	userID  string
+	ownerID string
+	grants  []repos.Share
+	// limit — наибольшая роль, которую допускают права ключа API запроса.
+	limit Role
+}
+
+// scopeLimit возвращает наибольшую роль, которую допускают права ключа API запроса;
+// запросы пользователей с токеном правами не ограничены.
+func scopeLimit(ctx context.Context) Role {
+	switch {
+	case auth.HasScope(ctx, auth.ScopeEventsWrite):
+		return RoleOwner
+	case auth.HasScope(ctx, auth.ScopeEventsRead):
+		return RoleReader
+	default:
+		return ""
+	}
+}
+
+// requireWriteScope проверяет, что права запроса допускают изменения.
+func requireWriteScope(ctx context.Context) error {
+	if !auth.HasScope(ctx, auth.ScopeEventsWrite) {
+		return fmt.Errorf("%w: %s scope required", ErrForbidden, auth.ScopeEventsWrite)
+	}
+	return nil
+}
+
+// accessTo загружает права пользователя запроса на календари владельца ownerID.
+func (s *EventsServiceImpl) accessTo(ctx context.Context, ownerID string) (access, error) {
+	userID, ok := auth.UserFrom(ctx)
+	if !ok {
+		return access{}, ErrUnauthenticated
+	}
+
+	a := access{userID: userID, ownerID: ownerID, limit: scopeLimit(ctx)}
+	if userID == ownerID {
+		return a, nil
+	}
+
+	grants, err := s.repo.ListGrants(ctx, ownerID, userID)
+	if err != nil {
+		return access{}, err
+	}
+	a.grants = grants
+	return a, nil
+}
+
+// role возвращает роль в календаре calendarID; пустой calendarID — роль, выданная
+// на все календари владельца.
+func (a access) role(calendarID string) Role {
+	if a.userID == a.ownerID {
+		return a.capped(RoleOwner)
+	}
+
+	var best Role
+	for _, g := range a.grants {
+		if g.CalendarID != "" && g.CalendarID != calendarID {
+			continue
+		}
+		if r := Role(g.Role); !best.includes(r) {
+			best = r
+		}
+	}
+	return a.capped(best)
+}
+
+// anyRole возвращает наибольшую роль хотя бы в одном календаре владельца.
+func (a access) anyRole() Role {
+	if a.userID == a.ownerID {
+		return a.capped(RoleOwner)
+	}
+
+	var best Role
+	for _, g := range a.grants {
+		if r := Role(g.Role); !best.includes(r) {
+			best = r
+		}
+	}
+	return a.capped(best)
+}
+
+// capped ограничивает роль r правами ключа API запроса.
+func (a access) capped(r Role) Role {
+	if !a.limit.includes(r) {
+		return a.limit
+	}
+	return r
+}
+
+func (a access) require(calendarID string, min Role) error {
+	if !a.role(calendarID).includes(min) {
+		return fmt.Errorf("%w: %s role required", ErrForbidden, min)
+	}
+	return nil
+}
+
+// requireCalendar проверяет, что у пользователя запроса есть роль min в календаре владельца.
+func (s *EventsServiceImpl) requireCalendar(ctx context.Context, ownerID, calendarID string, min Role) error {
+	a, err := s.accessTo(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	if err := a.require(calendarID, min); err != nil {
+		logger.FromContext(ctx).Debug("access denied", "owner_id", ownerID, "calendar_id", calendarID, "role", min)
+		return err
+	}
+	return nil
+}
+
+// requireEvent проверяет, что у пользователя запроса есть роль min в календаре события e.
+// Приглашённый на событие участник может его читать.
+func (s *EventsServiceImpl) requireEvent(ctx context.Context, e repos.Event, min Role) error {
+	a, err := s.accessTo(ctx, e.OwnerID)
+	if err != nil {
+		return err
+	}
+	if a.role(e.CalendarID).includes(min) {
+		return nil
+	}
+
+	if RoleReader.includes(min) {
+		invited, err := s.isInvited(ctx, e, a.userID)
+		if err != nil {
+			return err
+		}
+		if invited {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s role required", ErrForbidden, min)
+}
+
+// isInvited сообщает, приглашён ли userID на событие e (для вхождения — на серию).
+func (s *EventsServiceImpl) isInvited(ctx context.Context, e repos.Event, userID string) (bool, error) {
+	id := attendeesEventID(e)
+	attendees, err := s.repo.ListAttendees(ctx, []string{id})
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(attendees[id], func(a repos.Attendee) bool {
+		return a.UserID == userID
+	}), nil
+}
+
+// readableCalendars сужает фильтр по календарям calendarIDs (nil — все календари) владельца
+// до тех, которые пользователь запроса может читать. Для самого владельца фильтр не меняется.
+// Если читать нечего — ErrForbidden.
+func (s *EventsServiceImpl) readableCalendars(ctx context.Context, ownerID string, calendarIDs []string) ([]string, error) {
+	a, err := s.accessTo(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.calendarsWithRole(ctx, a, calendarIDs, RoleReader)
+}
+
+// calendarsWithRole сужает фильтр по календарям calendarIDs (nil — все календари) владельца
+// до тех, в которых у пользователя с правами a есть роль min. Для самого владельца фильтр
+// не меняется. Если таких календарей нет — ErrForbidden.
+func (s *EventsServiceImpl) calendarsWithRole(ctx context.Context, a access, calendarIDs []string, min Role) ([]string, error) {
+	ownerID := a.ownerID
+	if a.userID == ownerID {
+		return calendarIDs, nil
+	}
+
+	calendars, err := s.repo.ListCalendars(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := []string{}
+	for _, c := range calendars {
+		if calendarIDs != nil && !slices.Contains(calendarIDs, c.ID) {
+			continue
+		}
+		if a.role(c.ID).includes(min) {
+			allowed = append(allowed, c.ID)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, fmt.Errorf("%w: no calendars of %s with %s role", ErrForbidden, ownerID, min)
+	}
+	return allowed, nil
+}
+
+// ShareAccess выдаёт пользователю sh.GranteeID роль sh.Role в календаре sh.CalendarID
+// владельца sh.OwnerID (или во всех его календарях, если CalendarID пустой) либо меняет
+// уже выданную роль. Выдавать доступ может владелец и пользователи с ролью owner.
+func (s *EventsServiceImpl) ShareAccess(ctx context.Context, sh *repos.Share) error {
+	if sh.OwnerID == "" || sh.GranteeID == "" {
+		return fmt.Errorf("%w: owner and grantee are required", ErrInvalidShare)
+	}
+	if sh.GranteeID == sh.OwnerID {
+		return fmt.Errorf("%w: owner already has full access", ErrInvalidShare)
+	}
+	if _, err := ParseRole(sh.Role); err != nil {
+		return err
+	}
+
+	if sh.CalendarID != "" {
+		c, err := s.repo.GetCalendar(ctx, sh.CalendarID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: calendar %s not found", ErrInvalidShare, sh.CalendarID)
+		}
+		if err != nil {
+			return err
+		}
+		if c.OwnerID != sh.OwnerID {
+			return fmt.Errorf("%w: calendar %s belongs to another owner", ErrInvalidShare, sh.CalendarID)
+		}
+	}
+
+	if err := s.requireCalendar(ctx, sh.OwnerID, sh.CalendarID, RoleOwner); err != nil {
+		return err
+	}
+	return s.repo.SaveShare(ctx, sh)
+}
+
+// ListShares возвращает доступы к календарям владельца, которыми пользователь запроса
+// может управлять.
+func (s *EventsServiceImpl) ListShares(ctx context.Context, ownerID string) ([]repos.Share, error) {
+	a, err := s.accessTo(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.anyRole().includes(RoleOwner) {
+		return nil, fmt.Errorf("%w: %s role required", ErrForbidden, RoleOwner)
+	}
+
+	shares, err := s.repo.ListShares(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(shares, func(sh repos.Share) bool {
+		return !a.role(sh.CalendarID).includes(RoleOwner)
+	}), nil
+}
+
+// RevokeAccess отзывает доступ по ID. Кроме тех, кто может выдавать доступ,
+// от него может отказаться сам получивший.
+func (s *EventsServiceImpl) RevokeAccess(ctx context.Context, id string) error {
+	sh, err := s.repo.GetShare(ctx, id)
+	if err != nil {
+		return err
+	}
+	if userID, _ := auth.UserFrom(ctx); userID != sh.GranteeID {
+		if err := s.requireCalendar(ctx, sh.OwnerID, sh.CalendarID, RoleOwner); err != nil {
+			return err
+		}
+	} else if err := requireWriteScope(ctx); err != nil {
+		return err
+	}
+	return s.repo.DeleteShare(ctx, id)
+}
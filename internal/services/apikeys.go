@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"calendar/internal/auth"
+	"calendar/internal/repos"
+)
+
+// ErrInvalidAPIKey — ключ API задан некорректно.
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// APIKeysRepo — хранилище ключей API.
+type APIKeysRepo interface {
+	CreateAPIKey(ctx context.Context, k *repos.APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*repos.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*repos.APIKey, error)
+	ListAPIKeys(ctx context.Context, ownerID string) ([]repos.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
+
+// MintAPIKey выпускает ключ k без проверки прав запроса (например, из командной строки)
+// и возвращает сам ключ: он показывается один раз, хранится только хэш.
+func (s *EventsServiceImpl) MintAPIKey(ctx context.Context, k *repos.APIKey) (string, error) {
+	if k.OwnerID == "" || k.Name == "" {
+		return "", fmt.Errorf("%w: owner and name are required", ErrInvalidAPIKey)
+	}
+	if len(k.Scopes) == 0 {
+		return "", fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKey)
+	}
+	for _, scope := range k.Scopes {
+		if !slices.Contains(auth.Scopes, scope) {
+			return "", fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKey, scope)
+		}
+	}
+
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return "", err
+	}
+	if k.ID == "" {
+		k.ID = uuid.New().String()
+	}
+	k.Prefix, k.Hash = prefix, hash
+
+	if err := s.repo.CreateAPIKey(ctx, k); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// CreateAPIKey выпускает ключ k по запросу пользователя. Пользователь может выпускать ключи
+// от своего имени без права admin; ключи для других и ключи с правом admin выпускает только admin.
+func (s *EventsServiceImpl) CreateAPIKey(ctx context.Context, k *repos.APIKey) (string, error) {
+	if err := s.requireKeysOf(ctx, k.OwnerID); err != nil {
+		return "", err
+	}
+	if slices.Contains(k.Scopes, auth.ScopeAdmin) && !auth.HasScope(ctx, auth.ScopeAdmin) {
+		return "", fmt.Errorf("%w: %s scope required", ErrForbidden, auth.ScopeAdmin)
+	}
+	return s.MintAPIKey(ctx, k)
+}
+
+// ListAPIKeys возвращает ключи владельца, включая отозванные.
+func (s *EventsServiceImpl) ListAPIKeys(ctx context.Context, ownerID string) ([]repos.APIKey, error) {
+	if err := s.requireKeysOf(ctx, ownerID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAPIKeys(ctx, ownerID)
+}
+
+// RevokeAPIKey отзывает ключ по ID; отозванный ключ перестаёт приниматься сразу.
+func (s *EventsServiceImpl) RevokeAPIKey(ctx context.Context, id string) error {
+	k, err := s.repo.GetAPIKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.requireKeysOf(ctx, k.OwnerID); err != nil {
+		return err
+	}
+	return s.repo.RevokeAPIKey(ctx, id)
+}
+
+// ExportFeed возвращает события ownerID, как ExportEvents от его имени, по ключу API из URL
+// подписки .ics: клиенты подписок не умеют передавать заголовок Authorization. Ключ должен
+// принадлежать ownerID и иметь право feed:read (или events:read); иначе — ErrUnauthenticated.
+func (s *EventsServiceImpl) ExportFeed(ctx context.Context, ownerID, token string) ([]repos.Event, error) {
+	k, err := s.repo.GetAPIKeyByHash(ctx, auth.HashAPIKey(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: invalid feed token", ErrUnauthenticated)
+	}
+	if err != nil {
+		return nil, err
+	}
+	readable := slices.Contains(k.Scopes, auth.ScopeFeedRead) || auth.HasScope(auth.WithScopes(ctx, k.Scopes), auth.ScopeEventsRead)
+	if k.OwnerID != ownerID || !readable {
+		return nil, fmt.Errorf("%w: invalid feed token", ErrUnauthenticated)
+	}
+
+	ctx = auth.WithScopes(auth.WithUser(ctx, ownerID), []string{auth.ScopeEventsRead})
+	return s.ExportEvents(ctx, ownerID)
+}
+
+// requireKeysOf проверяет, что пользователь запроса может управлять ключами ownerID:
+// своими — с токеном, любыми — с правом admin. Ключ без права admin ключами не управляет.
+func (s *EventsServiceImpl) requireKeysOf(ctx context.Context, ownerID string) error {
+	userID, ok := auth.UserFrom(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if auth.HasScope(ctx, auth.ScopeAdmin) {
+		return nil
+	}
+	if _, limited := auth.ScopesFrom(ctx); limited || userID != ownerID {
+		return fmt.Errorf("%w: %s scope required", ErrForbidden, auth.ScopeAdmin)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"calendar/internal/auth"
+	"calendar/internal/repos"
+)
+
+// Ответы участников (RSVP), совпадают с PARTSTAT из RFC 5545.
+const (
+	StatusNeedsAction = "needs-action"
+	StatusAccepted    = "accepted"
+	StatusDeclined    = "declined"
+	StatusTentative   = "tentative"
+)
+
+// ErrInvalidAttendee — участник или его ответ заданы некорректно.
+var ErrInvalidAttendee = errors.New("invalid attendee")
+
+// AttendeesRepo — хранилище участников событий.
+type AttendeesRepo interface {
+	AddAttendee(ctx context.Context, a *repos.Attendee) error
+	UpdateAttendeeStatus(ctx context.Context, a *repos.Attendee) error
+	RemoveAttendee(ctx context.Context, eventID, userID string) error
+	ListAttendees(ctx context.Context, eventIDs []string) (map[string][]repos.Attendee, error)
+}
+
+func validateStatus(status string) error {
+	switch status {
+	case StatusNeedsAction, StatusAccepted, StatusDeclined, StatusTentative:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidAttendee, status)
+	}
+}
+
+// attendeesOwner проверяет, что пользователь запроса может менять участников события eventID
+// (роль writer, а ответ и отказ от приглашения — ещё и сам участник userID), и возвращает ID
+// события, которому принадлежат участники: у переопределения вхождения они общие с серией.
+func (s *EventsServiceImpl) attendeesOwner(ctx context.Context, eventID, userID string) (string, error) {
+	e, err := s.repo.GetEvent(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	if current, ok := auth.UserFrom(ctx); !ok || userID == "" || current != userID {
+		if err := s.requireEvent(ctx, *e, RoleWriter); err != nil {
+			return "", err
+		}
+	} else if err := requireWriteScope(ctx); err != nil {
+		return "", err
+	}
+	return attendeesEventID(*e), nil
+}
+
+// AddAttendee приглашает пользователя на событие (для вхождения серии — на всю серию).
+func (s *EventsServiceImpl) AddAttendee(ctx context.Context, a *repos.Attendee) error {
+	if a.UserID == "" {
+		return fmt.Errorf("%w: user_id is required", ErrInvalidAttendee)
+	}
+	if a.Status != "" {
+		if err := validateStatus(a.Status); err != nil {
+			return err
+		}
+	}
+
+	eventID, err := s.attendeesOwner(ctx, a.EventID, "")
+	if err != nil {
+		return err
+	}
+	a.EventID = eventID
+	return s.repo.AddAttendee(ctx, a)
+}
+
+// UpdateAttendeeStatus сохраняет ответ участника.
+func (s *EventsServiceImpl) UpdateAttendeeStatus(ctx context.Context, a *repos.Attendee) error {
+	if err := validateStatus(a.Status); err != nil {
+		return err
+	}
+
+	eventID, err := s.attendeesOwner(ctx, a.EventID, a.UserID)
+	if err != nil {
+		return err
+	}
+	a.EventID = eventID
+	return s.repo.UpdateAttendeeStatus(ctx, a)
+}
+
+// RemoveAttendee отзывает приглашение.
+func (s *EventsServiceImpl) RemoveAttendee(ctx context.Context, eventID, userID string) error {
+	eventID, err := s.attendeesOwner(ctx, eventID, userID)
+	if err != nil {
+		return err
+	}
+	return s.repo.RemoveAttendee(ctx, eventID, userID)
+}
+
+// withAttendees заполняет Attendees у events; вхождения и переопределения получают участников серии.
+func (s *EventsServiceImpl) withAttendees(ctx context.Context, events []repos.Event) error {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, attendeesEventID(e))
+	}
+
+	attendees, err := s.repo.ListAttendees(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		events[i].Attendees = attendees[attendeesEventID(events[i])]
+	}
+	return nil
+}
+
+func attendeesEventID(e repos.Event) string {
+	if e.RecurringEventID != "" {
+		return e.RecurringEventID
+	}
+	return e.ID
+}
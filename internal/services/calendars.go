@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"calendar/internal/repos"
+)
+
+// ErrInvalidCalendar — календарь задан некорректно или не принадлежит владельцу события.
+var ErrInvalidCalendar = errors.New("invalid calendar")
+
+// CalendarsRepo задаёт контракт работы с календарями, который нужен сервисам.
+type CalendarsRepo interface {
+	CreateCalendar(ctx context.Context, c *repos.Calendar) error
+	GetCalendar(ctx context.Context, id string) (*repos.Calendar, error)
+	GetDefaultCalendar(ctx context.Context, ownerID string) (*repos.Calendar, error)
+	EnsureDefaultCalendar(ctx context.Context, ownerID string) (*repos.Calendar, error)
+	ListCalendars(ctx context.Context, ownerID string) ([]repos.Calendar, error)
+	UpdateCalendar(ctx context.Context, c *repos.Calendar, fields repos.CalendarFields) error
+	DeleteCalendar(ctx context.Context, id string) error
+}
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// CreateCalendar создаёт новый календарь; часовой пояс по умолчанию — UTC.
+// Создавать календари может владелец и пользователи с ролью owner на все его календари.
+func (s *EventsServiceImpl) CreateCalendar(ctx context.Context, c *repos.Calendar) error {
+	if c.OwnerID == "" || c.Name == "" {
+		return fmt.Errorf("%w: owner and name are required", ErrInvalidCalendar)
+	}
+	if c.TimeZone == "" {
+		c.TimeZone = "UTC"
+	}
+	if err := validateCalendar(*c); err != nil {
+		return err
+	}
+	if err := s.requireCalendar(ctx, c.OwnerID, "", RoleOwner); err != nil {
+		return err
+	}
+	return s.repo.CreateCalendar(ctx, c)
+}
+
+// GetCalendar возвращает календарь по ID; если его нет — sql.ErrNoRows.
+func (s *EventsServiceImpl) GetCalendar(ctx context.Context, id string) (*repos.Calendar, error) {
+	c, err := s.repo.GetCalendar(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireCalendar(ctx, c.OwnerID, c.ID, RoleFreeBusy); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ListCalendars возвращает календари владельца, к которым у пользователя запроса есть доступ.
+// У самого владельца календарь по умолчанию есть всегда.
+func (s *EventsServiceImpl) ListCalendars(ctx context.Context, ownerID string) ([]repos.Calendar, error) {
+	a, err := s.accessTo(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if a.userID == ownerID {
+		if _, err := s.repo.EnsureDefaultCalendar(ctx, ownerID); err != nil {
+			return nil, err
+		}
+	}
+
+	calendars, err := s.repo.ListCalendars(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	calendars = slices.DeleteFunc(calendars, func(c repos.Calendar) bool {
+		return !a.role(c.ID).includes(RoleFreeBusy)
+	})
+	if len(calendars) == 0 && a.userID != ownerID {
+		return nil, fmt.Errorf("%w: no calendars of %s are shared", ErrForbidden, ownerID)
+	}
+	return calendars, nil
+}
+
+// UpdateCalendar записывает в календарь c.ID поля fields из c (название, цвет, часовой пояс)
+// и заполняет c его текущим состоянием. Пустой цвет снимает цвет календаря; название и пояс
+// очистить нельзя.
+func (s *EventsServiceImpl) UpdateCalendar(ctx context.Context, c *repos.Calendar, fields repos.CalendarFields) error {
+	if fields.Has(repos.FieldCalendarName) && c.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCalendar)
+	}
+	if fields.Has(repos.FieldCalendarTimeZone) && c.TimeZone == "" {
+		return fmt.Errorf("%w: time zone is required", ErrInvalidCalendar)
+	}
+	if err := validateCalendar(*c); err != nil {
+		return err
+	}
+	if err := s.requireOwnCalendar(ctx, c.ID); err != nil {
+		return err
+	}
+	return s.repo.UpdateCalendar(ctx, c, fields)
+}
+
+// DeleteCalendar удаляет календарь вместе со всеми его событиями.
+func (s *EventsServiceImpl) DeleteCalendar(ctx context.Context, id string) error {
+	if err := s.requireOwnCalendar(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteCalendar(ctx, id)
+}
+
+// requireOwnCalendar проверяет, что у пользователя запроса есть роль owner в календаре id.
+func (s *EventsServiceImpl) requireOwnCalendar(ctx context.Context, id string) error {
+	c, err := s.repo.GetCalendar(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.requireCalendar(ctx, c.OwnerID, c.ID, RoleOwner)
+}
+
+// validateCalendar проверяет заполненные цвет и часовой пояс.
+func validateCalendar(c repos.Calendar) error {
+	if c.Color != "" && !colorPattern.MatchString(c.Color) {
+		return fmt.Errorf("%w: color must look like #rrggbb", ErrInvalidCalendar)
+	}
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			return fmt.Errorf("%w: unknown time zone %q", ErrInvalidCalendar, c.TimeZone)
+		}
+	}
+	return nil
+}
+
+// resolveTimeZone кладёт в e.TimeZone часовой пояс календаря события, если пояс события не задан.
+func (s *EventsServiceImpl) resolveTimeZone(ctx context.Context, e *repos.Event) error {
+	if e.TimeZone != "" {
+		return nil
+	}
+	c, err := s.repo.GetCalendar(ctx, e.CalendarID)
+	if err != nil {
+		return err
+	}
+	e.TimeZone = c.TimeZone
+	return nil
+}
+
+// resolveCalendar кладёт в e.CalendarID календарь владельца по умолчанию, если календарь не задан,
+// и проверяет, что заданный календарь принадлежит владельцу события.
+// Календарь по умолчанию создаётся, только если владелец — сам пользователь запроса; для
+// чужого владельца нужна роль writer хотя бы в одном его календаре, и календарь по умолчанию
+// у него уже должен быть.
+func (s *EventsServiceImpl) resolveCalendar(ctx context.Context, e *repos.Event) error {
+	if e.CalendarID == "" {
+		c, err := s.defaultCalendar(ctx, e.OwnerID)
+		if err != nil {
+			return err
+		}
+		e.CalendarID = c.ID
+		return nil
+	}
+
+	c, err := s.repo.GetCalendar(ctx, e.CalendarID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: calendar %s not found", ErrInvalidCalendar, e.CalendarID)
+	}
+	if err != nil {
+		return err
+	}
+	if c.OwnerID != e.OwnerID {
+		return fmt.Errorf("%w: calendar %s belongs to another owner", ErrInvalidCalendar, e.CalendarID)
+	}
+	return nil
+}
+
+// defaultCalendar возвращает календарь ownerID по умолчанию для записи в него события
+// пользователем запроса (см. resolveCalendar).
+func (s *EventsServiceImpl) defaultCalendar(ctx context.Context, ownerID string) (*repos.Calendar, error) {
+	a, err := s.accessTo(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if a.userID == ownerID {
+		return s.repo.EnsureDefaultCalendar(ctx, ownerID)
+	}
+	if !a.anyRole().includes(RoleWriter) {
+		return nil, fmt.Errorf("%w: %s role required", ErrForbidden, RoleWriter)
+	}
+
+	c, err := s.repo.GetDefaultCalendar(ctx, ownerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s has no default calendar", ErrInvalidCalendar, ownerID)
+	}
+	return c, err
+}
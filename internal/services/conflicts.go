@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"calendar/internal/repos"
+)
+
+// ErrInvalidTimeRange — событие заканчивается раньше, чем начинается.
+var ErrInvalidTimeRange = errors.New("end_time is before start_time")
+
+// ConflictHorizon — на сколько вперёд (от начала серии или от текущего момента,
+// если серия уже началась) вхождения серии проверяются на пересечения.
+const ConflictHorizon = 365 * 24 * time.Hour
+
+// ConflictPolicy задаёт, что делать с событием, которое пересекается с другими событиями владельца.
+type ConflictPolicy string
+
+const (
+	// ConflictsAllow — сохранять событие как есть.
+	ConflictsAllow ConflictPolicy = "allow"
+	// ConflictsReject — отклонять событие с ConflictError.
+	ConflictsReject ConflictPolicy = "reject"
+)
+
+// ParseConflictPolicy разбирает политику; пустая строка означает ConflictsAllow.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch ConflictPolicy(s) {
+	case "", ConflictsAllow:
+		return ConflictsAllow, nil
+	case ConflictsReject:
+		return ConflictsReject, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q", s)
+	}
+}
+
+// ConflictError — событие пересекается с другими событиями владельца.
+type ConflictError struct {
+	// Conflicts — пересекающиеся события (вхождения серий развёрнуты) в порядке (start_time, id),
+	// которые пользователь запроса может читать.
+	Conflicts []repos.Event
+	// Busy — время остальных пересечений: события из календарей, которые пользователь
+	// запроса читать не может, не раскрываются.
+	Busy []Interval
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("event conflicts with %d other events", len(e.Conflicts)+len(e.Busy))
+}
+
+// redactConflicts оставляет в *ConflictError err подробности только тех событий, которые
+// пользователь запроса может читать в календарях владельца ownerID; от остальных остаётся время.
+// Другие ошибки возвращаются как есть.
+func (s *EventsServiceImpl) redactConflicts(ctx context.Context, ownerID string, err error) error {
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		return err
+	}
+	a, accessErr := s.accessTo(ctx, ownerID)
+	if accessErr != nil {
+		return accessErr
+	}
+
+	redacted := &ConflictError{Busy: conflict.Busy}
+	for _, e := range conflict.Conflicts {
+		if a.role(e.CalendarID).includes(RoleReader) {
+			redacted.Conflicts = append(redacted.Conflicts, e)
+			continue
+		}
+		redacted.Busy = append(redacted.Busy, Interval{Start: e.StartTime, End: e.EndTime})
+	}
+	return redacted
+}
+
+// validateTimeRange проверяет, что событие не заканчивается раньше, чем начинается.
+func validateTimeRange(e repos.Event) error {
+	if e.EndTime.Before(e.StartTime) {
+		return ErrInvalidTimeRange
+	}
+	return nil
+}
+
+// conflictGuard возвращает проверку, которая отклоняет запись события c, если оно пересекается
+// с другими событиями владельца, или, если policy пересечения допускает, только блокировку
+// владельца: иначе запись могла бы вклиниться между проверкой и записью другого события.
+// С событиями и сериями с ID из exclude (и с их переопределениями) c не сравнивается.
+func conflictGuard(c repos.Event, policy ConflictPolicy, now time.Time, exclude ...string) *repos.Guard {
+	if policy != ConflictsReject {
+		return &repos.Guard{OwnerID: c.OwnerID}
+	}
+
+	from, to := c.StartTime, c.EndTime
+	if c.RRule != "" {
+		if from.Before(now) {
+			from = now
+		}
+		to = from.Add(ConflictHorizon)
+	}
+
+	return &repos.Guard{
+		OwnerID: c.OwnerID,
+		From:    from,
+		To:      to,
+		Check: func(existing []repos.Event) error {
+			conflicts, err := findConflicts(c, existing, from, to, exclude)
+			if err != nil {
+				return err
+			}
+			if len(conflicts) > 0 {
+				return &ConflictError{Conflicts: conflicts}
+			}
+			return nil
+		},
+	}
+}
+
+// findConflicts возвращает вхождения событий existing из окна [from, to), которые пересекаются
+// хотя бы с одним вхождением c. Касание границами и события нулевой длительности пересечением
+// не считаются.
+func findConflicts(c repos.Event, existing []repos.Event, from, to time.Time, exclude []string) ([]repos.Event, error) {
+	skip := map[string]struct{}{c.ID: {}}
+	for _, id := range exclude {
+		skip[id] = struct{}{}
+	}
+
+	others := make([]repos.Event, 0, len(existing)+1)
+	for _, e := range existing {
+		_, self := skip[e.ID]
+		_, series := skip[e.RecurringEventID]
+		if self || series {
+			continue
+		}
+		others = append(others, e)
+	}
+	// Переопределение заменяет вхождение своей серии, и с исходным вхождением не конфликтует.
+	if c.RecurringEventID != "" {
+		others = append(others, c)
+	}
+
+	busy, err := ExpandEvents(others, from, to)
+	if err != nil {
+		return nil, err
+	}
+	occurrences, err := ExpandEvents([]repos.Event{c}, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []repos.Event
+	for _, b := range busy {
+		if b.ID == c.ID {
+			continue
+		}
+		for _, o := range occurrences {
+			if o.StartTime.Before(b.EndTime) && b.StartTime.Before(o.EndTime) {
+				conflicts = append(conflicts, b)
+				break
+			}
+		}
+	}
+	return conflicts, nil
+}
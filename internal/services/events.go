@@ -2,25 +2,93 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
 
 	"calendar/internal/repos"
 )
 
 // EventsRepo задаёт контракт работы с хранилищем событий, который нужен сервисам.
 type EventsRepo interface {
-	CreateEvent(ctx context.Context, e *repos.Event) error
-	UpdateEvent(ctx context.Context, e *repos.Event) error
-	DeleteEvent(ctx context.Context, id string) error
-	ListEvents(ctx context.Context, ownerID string) ([]repos.Event, error)
-	GetAllEvents(ctx context.Context) ([]repos.Event, error)
+	CreateEvent(ctx context.Context, e *repos.Event, guard *repos.Guard) error
+	GetEvent(ctx context.Context, id string) (*repos.Event, error)
+	GetEventByUID(ctx context.Context, ownerID, uid string) (*repos.Event, error)
+	ListEventsByUID(ctx context.Context, ownerID, uid string) ([]repos.Event, error)
+	GetOverride(ctx context.Context, seriesID string, recurrenceID time.Time) (*repos.Event, error)
+	UpdateEvent(ctx context.Context, e *repos.Event, fields repos.EventFields, guard *repos.Guard) error
+	ExcludeOccurrence(ctx context.Context, seriesID string, recurrenceID time.Time, version int64) error
+	SplitSeries(ctx context.Context, series *repos.Event, at time.Time, next *repos.Event, guard *repos.Guard) error
+	DeleteEvent(ctx context.Context, id string, version int64) error
+	ListEvents(ctx context.Context, ownerID string, calendarIDs []string, after *repos.Cursor, limit int) ([]repos.Event, error)
+	ListEventsInRange(ctx context.Context, ownerID string, calendarIDs []string, from, to time.Time, after *repos.Cursor, limit int) ([]repos.Event, error)
+	ListBusyEvents(ctx context.Context, userIDs []string, calendarIDs map[string][]string, from, to time.Time) (map[string][]repos.Event, error)
+	AttendeesRepo
+	CalendarsRepo
+	SharesRepo
+	APIKeysRepo
 }
 
 // EventsService описывает, что нужно хендлерам для работы с событиями.
 type EventsService interface {
-	CreateEvent(ctx context.Context, e *repos.Event) error
-	UpdateEvent(ctx context.Context, e *repos.Event) error
-	DeleteEvent(ctx context.Context, id string) error
-	ListEvents(ctx context.Context, ownerID string) ([]repos.Event, error)
+	CreateEvent(ctx context.Context, e *repos.Event, conflicts ConflictPolicy) error
+	GetEvent(ctx context.Context, id string) (*repos.Event, error)
+	UpdateEvent(ctx context.Context, e *repos.Event, fields repos.EventFields, conflicts ConflictPolicy) error
+	UpdateOccurrence(ctx context.Context, e *repos.Event, fields repos.EventFields, recurrenceID time.Time, scope RecurrenceScope, conflicts ConflictPolicy) error
+	DeleteEvent(ctx context.Context, id string, version int64) error
+	DeleteOccurrence(ctx context.Context, id string, recurrenceID time.Time, scope RecurrenceScope, version int64) error
+	ListEvents(ctx context.Context, q ListQuery) (EventsPage, error)
+	ExportEvents(ctx context.Context, ownerID string) ([]repos.Event, error)
+	ExportFeed(ctx context.Context, ownerID, token string) ([]repos.Event, error)
+	GetEventsByUID(ctx context.Context, ownerID, uid string) ([]repos.Event, error)
+	ImportEvents(ctx context.Context, ownerID string, events []repos.Event) ([]ImportResult, error)
+	FreeBusy(ctx context.Context, userIDs []string, from, to time.Time) (map[string][]Interval, error)
+	SuggestTimes(ctx context.Context, q SuggestQuery) ([]Suggestion, error)
+
+	AddAttendee(ctx context.Context, a *repos.Attendee) error
+	UpdateAttendeeStatus(ctx context.Context, a *repos.Attendee) error
+	RemoveAttendee(ctx context.Context, eventID, userID string) error
+
+	CreateCalendar(ctx context.Context, c *repos.Calendar) error
+	GetCalendar(ctx context.Context, id string) (*repos.Calendar, error)
+	ListCalendars(ctx context.Context, ownerID string) ([]repos.Calendar, error)
+	UpdateCalendar(ctx context.Context, c *repos.Calendar, fields repos.CalendarFields) error
+	DeleteCalendar(ctx context.Context, id string) error
+
+	ShareAccess(ctx context.Context, sh *repos.Share) error
+	ListShares(ctx context.Context, ownerID string) ([]repos.Share, error)
+	RevokeAccess(ctx context.Context, id string) error
+
+	MintAPIKey(ctx context.Context, k *repos.APIKey) (string, error)
+	CreateAPIKey(ctx context.Context, k *repos.APIKey) (string, error)
+	ListAPIKeys(ctx context.Context, ownerID string) ([]repos.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
+
+// ListQuery — параметры выборки событий пользователя.
+type ListQuery struct {
+	// OwnerID — пользователь: в выдачу попадают его события и события, на которые он приглашён.
+	OwnerID string
+	// CalendarIDs — если не nil, в выдачу попадают только события из этих календарей.
+	CalendarIDs []string
+	// From и To задают окно [From, To); нулевые значения — выборка без окна,
+	// серии при этом не разворачиваются.
+	From time.Time
+	To   time.Time
+	// After — курсор, после которого начинается страница; nil — первая страница.
+	After *repos.Cursor
+	Limit int
+}
+
+// EventsPage — страница выдачи ListEvents.
+type EventsPage struct {
+	Events []repos.Event
+	// Next — курсор следующей страницы; nil, если страница последняя.
+	Next *repos.Cursor
 }
 
 // EventsServiceImpl — реализация сервиса событий.
@@ -35,22 +103,494 @@ func NewEventsService(repo EventsRepo) EventsService {
 	}
 }
 
-// CreateEvent создаёт новое событие.
-func (s *EventsServiceImpl) CreateEvent(ctx context.Context, e *repos.Event) error {
-	return s.repo.CreateEvent(ctx, e)
+// CreateEvent создаёт новое событие; без CalendarID — в календаре владельца по умолчанию.
+// Без TimeZone серия разворачивается в часовом поясе своего календаря.
+// С ConflictsReject событие, пересекающееся с другими событиями владельца, не создаётся,
+// а возвращается *ConflictError (без подробностей событий, которые пользователь запроса
+// не может читать); проверка и запись атомарны относительно любых других
+// записей событий владельца: все они берут его блокировку (см. repos.Guard).
+func (s *EventsServiceImpl) CreateEvent(ctx context.Context, e *repos.Event, conflicts ConflictPolicy) error {
+	if err := validateTimeRange(*e); err != nil {
+		return err
+	}
+	if err := validateRecurrence(*e); err != nil {
+		return err
+	}
+	if err := validateReminders(*e); err != nil {
+		return err
+	}
+	if err := s.resolveCalendar(ctx, e); err != nil {
+		return err
+	}
+	if err := s.requireCalendar(ctx, e.OwnerID, e.CalendarID, RoleWriter); err != nil {
+		return err
+	}
+	if err := s.resolveTimeZone(ctx, e); err != nil {
+		return err
+	}
+	return s.redactConflicts(ctx, e.OwnerID, s.repo.CreateEvent(ctx, e, conflictGuard(*e, conflicts, time.Now())))
+}
+
+// GetEvent возвращает событие по ID вместе с участниками; если события нет — sql.ErrNoRows.
+func (s *EventsServiceImpl) GetEvent(ctx context.Context, id string) (*repos.Event, error) {
+	e, err := s.repo.GetEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireEvent(ctx, *e, RoleReader); err != nil {
+		return nil, err
+	}
+	if e.RecurringEventID != "" {
+		series, err := s.repo.GetEvent(ctx, e.RecurringEventID)
+		if err != nil {
+			return nil, err
+		}
+		e.SeriesVersion = series.Version
+	}
+
+	events := []repos.Event{*e}
+	if err := s.withAttendees(ctx, events); err != nil {
+		return nil, err
+	}
+	return &events[0], nil
+}
+
+// UpdateEvent записывает в существующее событие (для серии — во всю серию целиком) поля fields
+// из e, в том числе пустые; остальные поля не меняются. Событие, переданное другому владельцу
+// без указания календаря (или с пустым календарём), попадает в его календарь по умолчанию;
+// пустой TimeZone из fields возвращает событию часовой пояс его календаря.
+// Пересечения с другими событиями обрабатываются так же, как в CreateEvent.
+// Если e.Version не 0, событие обновляется, только если оно всё ещё этой версии
+// (иначе repos.ErrVersionMismatch); после обновления e.Version — новая версия.
+// Для переопределения вхождения так же сверяется e.SeriesVersion с версией серии;
+// после обновления e.SeriesVersion — текущая версия серии.
+func (s *EventsServiceImpl) UpdateEvent(ctx context.Context, e *repos.Event, fields repos.EventFields, conflicts ConflictPolicy) error {
+	if fields.Has(repos.FieldRRule) && e.RRule != "" {
+		if _, err := parseRRule(*e); err != nil {
+			return err
+		}
+	}
+	if fields.Has(repos.FieldTimeZone) {
+		if _, err := eventLocation(*e); err != nil {
+			return err
+		}
+	}
+	if err := validateReminders(*e); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.GetEvent(ctx, e.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireEvent(ctx, *existing, RoleWriter); err != nil {
+		return err
+	}
+	if err := checkVersion(*existing, e.Version); err != nil {
+		return err
+	}
+	var series *repos.Event
+	if existing.RecurringEventID != "" {
+		if series, err = s.repo.GetEvent(ctx, existing.RecurringEventID); err != nil {
+			return err
+		}
+		if err := checkVersion(*series, e.SeriesVersion); err != nil {
+			return err
+		}
+	}
+	if existing.RecurringEventID != "" && fields.Has(repos.FieldCalendarID) && e.CalendarID != existing.CalendarID {
+		return fmt.Errorf("%w: occurrence override stays in the calendar of its series", ErrInvalidCalendar)
+	}
+	if fields.Has(repos.FieldOwnerID) && e.OwnerID == "" {
+		return fmt.Errorf("%w: owner is required", ErrInvalidCalendar)
+	}
+
+	updated := *existing
+	applyPatch(&updated, e, fields)
+	if err := validateTimeRange(updated); err != nil {
+		return err
+	}
+	if updated.OwnerID != existing.OwnerID || updated.CalendarID != existing.CalendarID {
+		if !fields.Has(repos.FieldCalendarID) {
+			updated.CalendarID = ""
+		}
+		if err := s.resolveCalendar(ctx, &updated); err != nil {
+			return err
+		}
+		if err := s.requireCalendar(ctx, updated.OwnerID, updated.CalendarID, RoleWriter); err != nil {
+			return err
+		}
+		e.CalendarID = updated.CalendarID
+		fields |= repos.FieldCalendarID
+	}
+	if fields.Has(repos.FieldTimeZone) {
+		if err := s.resolveTimeZone(ctx, &updated); err != nil {
+			return err
+		}
+		e.TimeZone = updated.TimeZone
+	}
+
+	err = s.repo.UpdateEvent(ctx, e, fields, conflictGuard(updated, conflicts, time.Now()))
+	if err == nil && series != nil {
+		e.SeriesVersion = series.Version
+	}
+	return s.redactConflicts(ctx, updated.OwnerID, err)
+}
+
+// UpdateOccurrence записывает поля fields из e во вхождение серии e.ID, начинающееся в recurrenceID:
+// только в него (переопределение) или в него и все последующие (разрез серии).
+// Пересечения с другими событиями обрабатываются так же, как в CreateEvent.
+// e.Version, если не 0, — ожидаемая версия серии; если задана и e.SeriesVersion (версия
+// переопределённого вхождения, как в GetEvent), то e.Version — ожидаемая версия переопределения,
+// а e.SeriesVersion — серии.
+func (s *EventsServiceImpl) UpdateOccurrence(ctx context.Context, e *repos.Event, fields repos.EventFields, recurrenceID time.Time, scope RecurrenceScope, conflicts ConflictPolicy) error {
+	series, err := s.occurrenceSeries(ctx, e.ID, recurrenceID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireEvent(ctx, *series, RoleWriter); err != nil {
+		return err
+	}
+	seriesVersion, overrideVersion := e.Version, int64(0)
+	if e.SeriesVersion != 0 {
+		seriesVersion, overrideVersion = e.SeriesVersion, e.Version
+	}
+	if err := checkVersion(*series, seriesVersion); err != nil {
+		return err
+	}
+	if err := validateReminders(*e); err != nil {
+		return err
+	}
+
+	switch scope {
+	case ScopeThis:
+		if fields&repos.RecurrenceFields != 0 {
+			return ErrInvalidRecurrence
+		}
+		if fields&(repos.FieldOwnerID|repos.FieldCalendarID) != 0 {
+			return fmt.Errorf("%w: occurrence override stays in the calendar of its series", ErrInvalidCalendar)
+		}
+
+		override, err := s.repo.GetOverride(ctx, series.ID, recurrenceID)
+		if errors.Is(err, sql.ErrNoRows) && overrideVersion != 0 {
+			// Клиент видел переопределение, которого больше нет.
+			return repos.ErrVersionMismatch
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			o := *series
+			o.ID = uuid.New().String()
+			o.StartTime = recurrenceID
+			o.EndTime = recurrenceID.Add(series.EndTime.Sub(series.StartTime))
+			o.RRule = ""
+			o.ExDates, o.RDates = nil, nil
+			o.RecurringEventID = series.ID
+			o.RecurrenceID = recurrenceID
+			applyPatch(&o, e, fields)
+			if err := validateTimeRange(o); err != nil {
+				return err
+			}
+			return s.redactConflicts(ctx, o.OwnerID, s.repo.CreateEvent(ctx, &o, conflictGuard(o, conflicts, time.Now())))
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(*override, overrideVersion); err != nil {
+			return err
+		}
+
+		updated := *override
+		applyPatch(&updated, e, fields)
+		if err := validateTimeRange(updated); err != nil {
+			return err
+		}
+
+		patch := *e
+		patch.ID = override.ID
+		// Переопределение обновляется, только если оно не изменилось с тех пор, как его прочитали
+		// выше (или, если клиент прислал его версию, с тех пор, как его прочитал клиент).
+		patch.Version = override.Version
+		patch.SeriesVersion = 0
+		return s.redactConflicts(ctx, updated.OwnerID, s.repo.UpdateEvent(ctx, &patch, fields, conflictGuard(updated, conflicts, time.Now())))
+
+	case ScopeFollowing:
+		// Начиная с первого вхождения «это и последующие» — это вся серия.
+		e.Version, e.SeriesVersion = seriesVersion, 0
+		if recurrenceID.Equal(series.StartTime) {
+			return s.UpdateEvent(ctx, e, fields, conflicts)
+		}
+
+		head, tail, err := splitSeries(*series, recurrenceID)
+		if err != nil {
+			return err
+		}
+		tail.ID = uuid.New().String()
+		applyPatch(&tail, e, fields)
+		if err := validateTimeRange(tail); err != nil {
+			return err
+		}
+		if err := validateRecurrence(tail); err != nil {
+			return err
+		}
+		if tail.OwnerID != series.OwnerID && !fields.Has(repos.FieldCalendarID) {
+			tail.CalendarID = ""
+		}
+		if err := s.resolveCalendar(ctx, &tail); err != nil {
+			return err
+		}
+		if err := s.requireCalendar(ctx, tail.OwnerID, tail.CalendarID, RoleWriter); err != nil {
+			return err
+		}
+		if err := s.resolveTimeZone(ctx, &tail); err != nil {
+			return err
+		}
+		// Вхождения продолжения не сравниваются с разрезаемой серией: с at и далее её заменяет tail.
+		guard := conflictGuard(tail, conflicts, time.Now(), series.ID)
+		head.Version = e.Version
+		return s.redactConflicts(ctx, tail.OwnerID, s.repo.SplitSeries(ctx, &head, recurrenceID, &tail, guard))
+
+	default:
+		return ErrInvalidRecurrence
+	}
+}
+
+// DeleteEvent удаляет событие по ID. Если version не 0, событие удаляется, только если
+// оно всё ещё этой версии (иначе repos.ErrVersionMismatch).
+func (s *EventsServiceImpl) DeleteEvent(ctx context.Context, id string, version int64) error {
+	e, err := s.repo.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.requireEvent(ctx, *e, RoleWriter); err != nil {
+		return err
+	}
+	if err := checkVersion(*e, version); err != nil {
+		return err
+	}
+	return s.repo.DeleteEvent(ctx, id, version)
+}
+
+// DeleteOccurrence удаляет вхождение серии id, начинающееся в recurrenceID,
+// либо только его (EXDATE), либо его и все последующие. version, если не 0, — ожидаемая версия серии.
+func (s *EventsServiceImpl) DeleteOccurrence(ctx context.Context, id string, recurrenceID time.Time, scope RecurrenceScope, version int64) error {
+	series, err := s.occurrenceSeries(ctx, id, recurrenceID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireEvent(ctx, *series, RoleWriter); err != nil {
+		return err
+	}
+	if err := checkVersion(*series, version); err != nil {
+		return err
+	}
+
+	switch scope {
+	case ScopeThis:
+		return s.repo.ExcludeOccurrence(ctx, series.ID, recurrenceID, version)
+
+	case ScopeFollowing:
+		if recurrenceID.Equal(series.StartTime) {
+			return s.repo.DeleteEvent(ctx, series.ID, version)
+		}
+
+		head, _, err := splitSeries(*series, recurrenceID)
+		if err != nil {
+			return err
+		}
+		head.Version = version
+		return s.repo.SplitSeries(ctx, &head, recurrenceID, nil, nil)
+
+	default:
+		return ErrInvalidRecurrence
+	}
+}
+
+// checkVersion возвращает repos.ErrVersionMismatch, если версия e не равна version; 0 — без проверки.
+// Окончательно версия сверяется в репозитории вместе с записью.
+func checkVersion(e repos.Event, version int64) error {
+	if version != 0 && e.Version != version {
+		return repos.ErrVersionMismatch
+	}
+	return nil
+}
+
+// occurrenceSeries загружает серию и проверяет, что у неё есть вхождение в recurrenceID.
+func (s *EventsServiceImpl) occurrenceSeries(ctx context.Context, id string, recurrenceID time.Time) (*repos.Event, error) {
+	series, err := s.repo.GetEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if series.RRule == "" {
+		return nil, ErrNotRecurring
+	}
+
+	ok, err := isOccurrence(*series, recurrenceID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNoSuchOccurrence
+	}
+	return series, nil
+}
+
+// ListEvents возвращает страницу событий пользователя в порядке (start_time, id) вместе с участниками.
+// Если задано окно, из БД выбираются только события, пересекающиеся с окном,
+// а серии разворачиваются во вхождения внутри окна. Другим пользователям достаются только
+// события из календарей, которые им доступны для чтения.
+func (s *EventsServiceImpl) ListEvents(ctx context.Context, q ListQuery) (EventsPage, error) {
+	calendarIDs, err := s.readableCalendars(ctx, q.OwnerID, q.CalendarIDs)
+	if err != nil {
+		return EventsPage{}, err
+	}
+	q.CalendarIDs = calendarIDs
+
+	page, err := s.listEvents(ctx, q)
+	if err != nil {
+		return EventsPage{}, err
+	}
+	if err := s.withAttendees(ctx, page.Events); err != nil {
+		return EventsPage{}, err
+	}
+	return page, nil
+}
+
+func (s *EventsServiceImpl) listEvents(ctx context.Context, q ListQuery) (EventsPage, error) {
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница.
+	if q.From.IsZero() && q.To.IsZero() {
+		events, err := s.repo.ListEvents(ctx, q.OwnerID, q.CalendarIDs, q.After, q.Limit+1)
+		if err != nil {
+			return EventsPage{}, err
+		}
+		return paginate(events, q.After, q.Limit), nil
+	}
+
+	events, err := s.repo.ListEventsInRange(ctx, q.OwnerID, q.CalendarIDs, q.From, q.To, q.After, q.Limit+1)
+	if err != nil {
+		return EventsPage{}, err
+	}
+	events, err = ExpandEvents(events, q.From, q.To)
+	if err != nil {
+		return EventsPage{}, err
+	}
+	return paginate(events, q.After, q.Limit), nil
 }
 
-// UpdateEvent обновляет существующее событие.
-func (s *EventsServiceImpl) UpdateEvent(ctx context.Context, e *repos.Event) error {
-	return s.repo.UpdateEvent(ctx, e)
+// exportBatchSize — сколько событий ExportEvents читает из БД за один запрос.
+const exportBatchSize = 500
+
+// ExportEvents возвращает все события пользователя без разворачивания серий —
+// вместе с переопределениями вхождений, в порядке (start_time, id). Как и в ListEvents,
+// другим пользователям достаются только события из доступных им календарей.
+func (s *EventsServiceImpl) ExportEvents(ctx context.Context, ownerID string) ([]repos.Event, error) {
+	calendarIDs, err := s.readableCalendars(ctx, ownerID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events []repos.Event
+		after  *repos.Cursor
+	)
+	for {
+		batch, err := s.repo.ListEvents(ctx, ownerID, calendarIDs, after, exportBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, batch...)
+		if len(batch) < exportBatchSize {
+			return events, nil
+		}
+		next := cursorOf(batch[len(batch)-1])
+		after = &next
+	}
 }
 
-// DeleteEvent удаляет событие по ID.
-func (s *EventsServiceImpl) DeleteEvent(ctx context.Context, id string) error {
-	return s.repo.DeleteEvent(ctx, id)
+// GetEventsByUID возвращает событие владельца с iCalendar UID uid вместе с переопределениями
+// вхождений (серия первой); если такого события нет — sql.ErrNoRows.
+func (s *EventsServiceImpl) GetEventsByUID(ctx context.Context, ownerID, uid string) ([]repos.Event, error) {
+	events, err := s.repo.ListEventsByUID(ctx, ownerID, uid)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	if err := s.requireEvent(ctx, events[0], RoleReader); err != nil {
+		return nil, err
+	}
+	return events, nil
 }
 
-// ListEvents возвращает все события конкретного владельца.
-func (s *EventsServiceImpl) ListEvents(ctx context.Context, ownerID string) ([]repos.Event, error) {
-	return s.repo.ListEvents(ctx, ownerID)
+func cursorOf(e repos.Event) repos.Cursor {
+	return repos.Cursor{StartTime: e.StartTime, ID: e.ID}
+}
+
+// cursorLess сравнивает позиции в порядке (start_time, id).
+func cursorLess(a, b repos.Cursor) bool {
+	if !a.StartTime.Equal(b.StartTime) {
+		return a.StartTime.Before(b.StartTime)
+	}
+	return a.ID < b.ID
+}
+
+// paginate отбрасывает из упорядоченного events всё до курсора after включительно
+// и оставляет не больше limit событий.
+func paginate(events []repos.Event, after *repos.Cursor, limit int) EventsPage {
+	if after != nil {
+		i := sort.Search(len(events), func(i int) bool {
+			return cursorLess(*after, cursorOf(events[i]))
+		})
+		events = events[i:]
+	}
+
+	page := EventsPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		next := cursorOf(page.Events[limit-1])
+		page.Next = &next
+	}
+	return page
+}
+
+type occurrenceKey struct {
+	seriesID string
+	start    int64
+}
+
+// ExpandEvents разворачивает серии из events в окне [from, to), заменяя переопределённые
+// вхождения их переопределениями, и сортирует результат по (start_time, id).
+// Переопределения должны быть в events вместе со своими сериями.
+func ExpandEvents(events []repos.Event, from, to time.Time) ([]repos.Event, error) {
+	overridden := make(map[occurrenceKey]struct{})
+	for _, e := range events {
+		if e.RecurringEventID != "" {
+			overridden[occurrenceKey{e.RecurringEventID, e.RecurrenceID.UnixNano()}] = struct{}{}
+		}
+	}
+
+	result := make([]repos.Event, 0, len(events))
+	for _, e := range events {
+		if e.RRule == "" {
+			if overlaps(e.StartTime, e.EndTime, from, to) {
+				result = append(result, e)
+			}
+			continue
+		}
+
+		occurrences, err := expandEvent(e, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range occurrences {
+			if _, ok := overridden[occurrenceKey{e.ID, o.RecurrenceID.UnixNano()}]; ok {
+				continue
+			}
+			result = append(result, o)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return cursorLess(cursorOf(result[i]), cursorOf(result[j]))
+	})
+	return result, nil
 }
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"calendar/internal/repos"
+)
+
+// Ограничения запроса занятости.
+const (
+	MaxFreeBusyUsers  = 100
+	MaxFreeBusyWindow = 366 * 24 * time.Hour
+)
+
+// ErrInvalidFreeBusy — запрос занятости задан некорректно.
+var ErrInvalidFreeBusy = errors.New("invalid free/busy query")
+
+// Interval — промежуток времени [Start, End).
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// FreeBusy возвращает для каждого пользователя из userIDs занятые промежутки в окне [from, to):
+// вхождения его событий и событий, на которые он приглашён и не отказался, обрезанные по окну,
+// объединённые и упорядоченные по времени. Подробности событий не раскрываются.
+// У пользователя запроса должна быть хотя бы роль free-busy в одном из календарей каждого из userIDs;
+// если она выдана не на все календари пользователя, учитываются только его события из календарей,
+// где она есть, без приглашений.
+func (s *EventsServiceImpl) FreeBusy(ctx context.Context, userIDs []string, from, to time.Time) (map[string][]Interval, error) {
+	if err := validateFreeBusy(userIDs, from, to); err != nil {
+		return nil, err
+	}
+	calendarIDs := make(map[string][]string)
+	for _, id := range userIDs {
+		a, err := s.accessTo(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		// Владелец и роль на все календари видят всю занятость, включая приглашения.
+		if a.role("").includes(RoleFreeBusy) {
+			continue
+		}
+		ids, err := s.calendarsWithRole(ctx, a, nil, RoleFreeBusy)
+		if err != nil {
+			return nil, err
+		}
+		calendarIDs[id] = ids
+	}
+
+	events, err := s.repo.ListBusyEvents(ctx, userIDs, calendarIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	busy := make(map[string][]Interval, len(userIDs))
+	for _, id := range userIDs {
+		occurrences, err := ExpandEvents(events[id], from, to)
+		if err != nil {
+			return nil, err
+		}
+		busy[id] = mergeBusy(occurrences, from, to)
+	}
+	return busy, nil
+}
+
+func validateFreeBusy(userIDs []string, from, to time.Time) error {
+	if len(userIDs) == 0 || len(userIDs) > MaxFreeBusyUsers {
+		return fmt.Errorf("%w: between 1 and %d users are required", ErrInvalidFreeBusy, MaxFreeBusyUsers)
+	}
+	for _, id := range userIDs {
+		if id == "" {
+			return fmt.Errorf("%w: empty user id", ErrInvalidFreeBusy)
+		}
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidFreeBusy)
+	}
+	if to.Sub(from) > MaxFreeBusyWindow {
+		return fmt.Errorf("%w: window is longer than %d days", ErrInvalidFreeBusy, MaxFreeBusyWindow/(24*time.Hour))
+	}
+	return nil
+}
+
+// mergeBusy сводит упорядоченные по началу вхождения в непересекающиеся промежутки внутри
+// окна [from, to). Примыкающие промежутки склеиваются, события нулевой длительности пропускаются.
+func mergeBusy(occurrences []repos.Event, from, to time.Time) []Interval {
+	busy := []Interval{}
+	for _, o := range occurrences {
+		start, end := o.StartTime, o.EndTime
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !start.Before(end) {
+			continue
+		}
+
+		if n := len(busy); n > 0 && !start.After(busy[n-1].End) {
+			if end.After(busy[n-1].End) {
+				busy[n-1].End = end
+			}
+			continue
+		}
+		busy = append(busy, Interval{Start: start, End: end})
+	}
+	return busy
+}
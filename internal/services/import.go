@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"calendar/internal/repos"
+)
+
+// ImportStatus — итог импорта одного события.
+type ImportStatus string
+
+const (
+	ImportCreated ImportStatus = "created"
+	ImportUpdated ImportStatus = "updated"
+	ImportSkipped ImportStatus = "skipped"
+)
+
+var (
+	// ErrUnchanged — импортируемое событие совпадает с уже сохранённым.
+	ErrUnchanged = errors.New("event is unchanged")
+	// ErrSeriesNotFound — для импортируемого вхождения не найдена серия с тем же UID.
+	ErrSeriesNotFound = errors.New("recurring event with this UID not found")
+)
+
+// ImportResult — итог импорта одного события ImportEvents.
+type ImportResult struct {
+	Status ImportStatus
+	// EventID — ID созданного или обновлённого события.
+	EventID string
+	// Err — причина пропуска (ImportSkipped).
+	Err error
+}
+
+// ImportEvents создаёт или обновляет события владельца ownerID, сопоставляя их с уже
+// сохранёнными по ICalUID. События с заполненным RecurrenceID — переопределения вхождений
+// серии с тем же UID; они применяются после серий, поэтому серия и её вхождения могут
+// прийти в одном календаре. Результаты возвращаются в порядке events.
+//
+// Обновление заменяет содержимое события целиком (repos.ContentFields): поля, пустые
+// в импортируемом событии, очищаются; владелец и календарь не меняются. «Плавающее» время
+// (repos.Event.Floating) считается местным временем календаря, в который попадает событие. Импортировать может пользователь с ролью writer хотя бы
+// в одном календаре владельца; события календарей, где этой роли нет, пропускаются.
+func (s *EventsServiceImpl) ImportEvents(ctx context.Context, ownerID string, events []repos.Event) ([]ImportResult, error) {
+	a, err := s.accessTo(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.anyRole().includes(RoleWriter) {
+		return nil, fmt.Errorf("%w: %s role required", ErrForbidden, RoleWriter)
+	}
+
+	results := make([]ImportResult, len(events))
+
+	for _, overrides := range []bool{false, true} {
+		for i, e := range events {
+			if isOverride := !e.RecurrenceID.IsZero(); isOverride != overrides {
+				continue
+			}
+
+			e.OwnerID = ownerID
+			var err error
+			if overrides {
+				results[i], err = s.importOverride(ctx, a, e)
+			} else {
+				results[i], err = s.importEvent(ctx, a, e)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return results, nil
+}
+
+func (s *EventsServiceImpl) importEvent(ctx context.Context, a access, e repos.Event) (ImportResult, error) {
+	if err := validateTimeRange(e); err != nil {
+		return skipped(err), nil
+	}
+	if err := validateRecurrence(e); err != nil {
+		return skipped(err), nil
+	}
+	if err := validateReminders(e); err != nil {
+		return skipped(err), nil
+	}
+
+	existing, err := s.repo.GetEventByUID(ctx, e.OwnerID, e.ICalUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		e.ID = uuid.New().String()
+		if err := s.resolveCalendar(ctx, &e); errors.Is(err, ErrInvalidCalendar) {
+			return skipped(err), nil
+		} else if err != nil {
+			return ImportResult{}, err
+		}
+		if err := a.require(e.CalendarID, RoleWriter); err != nil {
+			return skipped(err), nil
+		}
+		if err := s.resolveTimeZone(ctx, &e); err != nil {
+			return ImportResult{}, err
+		}
+		if err := anchorFloating(&e); err != nil {
+			return skipped(err), nil
+		}
+		if err := s.repo.CreateEvent(ctx, &e, conflictGuard(e, ConflictsAllow, time.Now())); err != nil {
+			return ImportResult{}, err
+		}
+		return ImportResult{Status: ImportCreated, EventID: e.ID}, nil
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if err := a.require(existing.CalendarID, RoleWriter); err != nil {
+		return skipped(err), nil
+	}
+
+	return s.importUpdate(ctx, existing, e)
+}
+
+func (s *EventsServiceImpl) importOverride(ctx context.Context, a access, e repos.Event) (ImportResult, error) {
+	if e.RRule != "" || len(e.ExDates) > 0 || len(e.RDates) > 0 {
+		return skipped(ErrInvalidRecurrence), nil
+	}
+	if err := validateTimeRange(e); err != nil {
+		return skipped(err), nil
+	}
+	if err := validateReminders(e); err != nil {
+		return skipped(err), nil
+	}
+
+	series, err := s.repo.GetEventByUID(ctx, e.OwnerID, e.ICalUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return skipped(ErrSeriesNotFound), nil
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if err := a.require(series.CalendarID, RoleWriter); err != nil {
+		return skipped(err), nil
+	}
+	if series.RRule == "" {
+		return skipped(ErrNotRecurring), nil
+	}
+	e.TimeZone = series.TimeZone
+	if err := anchorFloating(&e); err != nil {
+		return skipped(err), nil
+	}
+	ok, err := isOccurrence(*series, e.RecurrenceID)
+	if err != nil {
+		return skipped(err), nil
+	}
+	if !ok {
+		return skipped(ErrNoSuchOccurrence), nil
+	}
+
+	e.RecurringEventID = series.ID
+	e.CalendarID = series.CalendarID
+	existing, err := s.repo.GetOverride(ctx, series.ID, e.RecurrenceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		e.ID = uuid.New().String()
+		if err := s.repo.CreateEvent(ctx, &e, conflictGuard(e, ConflictsAllow, time.Now())); err != nil {
+			return ImportResult{}, err
+		}
+		return ImportResult{Status: ImportCreated, EventID: e.ID}, nil
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	return s.importUpdate(ctx, existing, e)
+}
+
+// importUpdate переносит в existing поля импортированного события e.
+func (s *EventsServiceImpl) importUpdate(ctx context.Context, existing *repos.Event, e repos.Event) (ImportResult, error) {
+	// Событие без TZID разворачивается в поясе своего календаря, как при создании.
+	e.CalendarID = existing.CalendarID
+	if err := s.resolveTimeZone(ctx, &e); err != nil {
+		return ImportResult{}, err
+	}
+	if err := anchorFloating(&e); err != nil {
+		return skipped(err), nil
+	}
+	if sameEvent(*existing, e) {
+		return ImportResult{Status: ImportSkipped, EventID: existing.ID, Err: ErrUnchanged}, nil
+	}
+
+	patch := e
+	patch.ID = existing.ID
+	if err := s.repo.UpdateEvent(ctx, &patch, repos.ContentFields, conflictGuard(patch, ConflictsAllow, time.Now())); err != nil {
+		return ImportResult{}, err
+	}
+	return ImportResult{Status: ImportUpdated, EventID: existing.ID}, nil
+}
+
+// anchorFloating переносит «плавающее» время события e (см. repos.Event.Floating) в его
+// часовой пояс e.TimeZone, сохраняя местное время.
+func anchorFloating(e *repos.Event) error {
+	if !e.Floating {
+		return nil
+	}
+	loc, err := eventLocation(*e)
+	if err != nil {
+		return err
+	}
+
+	in := func(t time.Time) time.Time {
+		if t.IsZero() {
+			return t
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	}
+	inAll := func(times []time.Time) []time.Time {
+		res := make([]time.Time, 0, len(times))
+		for _, t := range times {
+			res = append(res, in(t))
+		}
+		return res
+	}
+
+	e.StartTime, e.EndTime, e.RecurrenceID = in(e.StartTime), in(e.EndTime), in(e.RecurrenceID)
+	if e.ExDates != nil {
+		e.ExDates = inAll(e.ExDates)
+	}
+	if e.RDates != nil {
+		e.RDates = inAll(e.RDates)
+	}
+	e.Floating = false
+	return nil
+}
+
+func skipped(err error) ImportResult {
+	return ImportResult{Status: ImportSkipped, Err: err}
+}
+
+// sameEvent сообщает, что импорт e ничего не изменит в сохранённом событии existing.
+func sameEvent(existing, e repos.Event) bool {
+	timesEqual := func(a, b time.Time) bool { return a.Equal(b) }
+
+	return existing.Title == e.Title &&
+		existing.Description == e.Description &&
+		existing.StartTime.Equal(e.StartTime) &&
+		existing.EndTime.Equal(e.EndTime) &&
+		existing.RRule == e.RRule &&
+		existing.TimeZone == e.TimeZone &&
+		slices.EqualFunc(existing.ExDates, e.ExDates, timesEqual) &&
+		slices.EqualFunc(existing.RDates, e.RDates, timesEqual) &&
+		slices.Equal(existing.Reminders, e.Reminders)
+}
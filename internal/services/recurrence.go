@@ -0,0 +1,308 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/teambition/rrule-go"
+
+	"calendar/internal/repos"
+)
+
+var (
+	// ErrInvalidRecurrence — правило повторения не удалось разобрать или оно противоречит событию.
+	ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+	// ErrNotRecurring — операция над вхождением применена к событию без правила повторения.
+	ErrNotRecurring = errors.New("event is not recurring")
+	// ErrNoSuchOccurrence — у серии нет вхождения, начинающегося в указанный момент.
+	ErrNoSuchOccurrence = errors.New("no such occurrence")
+	// ErrInvalidTimeZone — часовой пояс события не найден в базе IANA.
+	ErrInvalidTimeZone = errors.New("invalid time zone")
+)
+
+// RecurrenceScope задаёт, к каким вхождениям серии применяется изменение.
+type RecurrenceScope string
+
+const (
+	// ScopeThis — только к одному вхождению (создаётся или меняется переопределение).
+	ScopeThis RecurrenceScope = "this"
+	// ScopeFollowing — к вхождению и всем последующим (серия разрезается на две).
+	ScopeFollowing RecurrenceScope = "following"
+)
+
+// ParseRecurrenceScope разбирает область применения; пустая строка означает ScopeThis.
+func ParseRecurrenceScope(s string) (RecurrenceScope, error) {
+	switch RecurrenceScope(s) {
+	case "", ScopeThis:
+		return ScopeThis, nil
+	case ScopeFollowing:
+		return ScopeFollowing, nil
+	default:
+		return "", fmt.Errorf("unknown recurrence scope %q", s)
+	}
+}
+
+// eventLocation возвращает часовой пояс события; пустой пояс — UTC.
+func eventLocation(e repos.Event) (*time.Location, error) {
+	loc, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimeZone, e.TimeZone)
+	}
+	return loc, nil
+}
+
+// parseRRule разбирает RRULE события, привязывая его к началу события в часовом поясе события:
+// иначе BYDAY и время вхождений считались бы в UTC и «съезжали» бы при переходе на летнее время.
+func parseRRule(e repos.Event) (*rrule.RRule, error) {
+	loc, err := eventLocation(e)
+	if err != nil {
+		return nil, err
+	}
+	// «Плавающий» UNTIL без Z тоже задан в поясе события.
+	opt, err := rrule.StrToROptionInLocation(e.RRule, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	opt.Dtstart = e.StartTime.In(loc)
+
+	r, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	return r, nil
+}
+
+// recurrenceSet собирает RRULE, RDATE и EXDATE серии в один набор вхождений.
+func recurrenceSet(e repos.Event) (*rrule.Set, error) {
+	r, err := parseRRule(e)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &rrule.Set{}
+	set.RRule(r)
+	for _, t := range e.RDates {
+		set.RDate(t)
+	}
+	for _, t := range e.ExDates {
+		set.ExDate(t)
+	}
+	return set, nil
+}
+
+// validateRecurrence проверяет, что правило повторения и часовой пояс события корректны.
+func validateRecurrence(e repos.Event) error {
+	if _, err := eventLocation(e); err != nil {
+		return err
+	}
+	if e.RRule == "" {
+		if len(e.ExDates) > 0 || len(e.RDates) > 0 {
+			return fmt.Errorf("%w: exdates and rdates require rrule", ErrInvalidRecurrence)
+		}
+		return nil
+	}
+	if e.RecurringEventID != "" {
+		return fmt.Errorf("%w: occurrence override cannot recur", ErrInvalidRecurrence)
+	}
+	_, err := parseRRule(e)
+	return err
+}
+
+// overlaps сообщает, пересекается ли интервал [start, end) с окном [from, to).
+// Событие нулевой длительности попадает в окно, если его начало лежит внутри окна.
+func overlaps(start, end, from, to time.Time) bool {
+	if !start.Before(to) {
+		return false
+	}
+	if start.Equal(end) {
+		return !start.Before(from)
+	}
+	return end.After(from)
+}
+
+// expandEvent разворачивает серию во вхождения, пересекающиеся с окном [from, to).
+// Каждое вхождение — копия серии со сдвинутым временем и заполненным RecurrenceID.
+func expandEvent(e repos.Event, from, to time.Time) ([]repos.Event, error) {
+	set, err := recurrenceSet(e)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := e.EndTime.Sub(e.StartTime)
+
+	var occurrences []repos.Event
+	for _, start := range set.Between(from.Add(-duration), to, true) {
+		if !overlaps(start, start.Add(duration), from, to) {
+			continue
+		}
+
+		o := e
+		o.StartTime = start
+		o.EndTime = start.Add(duration)
+		o.RRule = ""
+		o.ExDates = nil
+		o.RDates = nil
+		o.RecurringEventID = e.ID
+		o.RecurrenceID = start
+		occurrences = append(occurrences, o)
+	}
+	return occurrences, nil
+}
+
+// isOccurrence сообщает, начинается ли в момент at одно из вхождений серии.
+func isOccurrence(e repos.Event, at time.Time) (bool, error) {
+	set, err := recurrenceSet(e)
+	if err != nil {
+		return false, err
+	}
+	return len(set.Between(at, at, true)) > 0, nil
+}
+
+// splitSeries разрезает серию в момент at: head содержит вхождения до at,
+// tail (с новым ID, который задаёт вызывающий) — at и все последующие.
+func splitSeries(e repos.Event, at time.Time) (head, tail repos.Event, err error) {
+	r, err := parseRRule(e)
+	if err != nil {
+		return head, tail, err
+	}
+
+	headOpt := r.OrigOptions
+	tailOpt := r.OrigOptions
+	headOpt.Dtstart = time.Time{}
+	tailOpt.Dtstart = time.Time{}
+
+	// COUNT считает вхождения самого RRULE, поэтому продолжению достаётся остаток.
+	if tailOpt.Count > 0 {
+		passed := r.Between(e.StartTime, at, true)
+		n := len(passed)
+		if n > 0 && passed[n-1].Equal(at) {
+			n--
+		}
+		tailOpt.Count -= n
+		if tailOpt.Count < 1 {
+			return head, tail, fmt.Errorf("%w: series rule is exhausted before %s", ErrInvalidRecurrence, at.Format(time.RFC3339))
+		}
+	}
+	headOpt.Count = 0
+	// Сравнивается момент, а не местное время: RRuleString пишет UNTIL в UTC, как RFC 5545
+	// требует при DTSTART в часовом поясе, а parseRRule снова привязывает правило к поясу серии.
+	headOpt.Until = at.Add(-time.Second)
+
+	head = e
+	head.RRule = headOpt.RRuleString()
+	head.ExDates, head.RDates = nil, nil
+
+	tail = e
+	tail.ID = ""
+	tail.ICalUID = ""
+	tail.StartTime = at
+	tail.EndTime = at.Add(e.EndTime.Sub(e.StartTime))
+	tail.RRule = tailOpt.RRuleString()
+	tail.ExDates, tail.RDates = nil, nil
+
+	for _, t := range e.ExDates {
+		if t.Before(at) {
+			head.ExDates = append(head.ExDates, t)
+		} else {
+			tail.ExDates = append(tail.ExDates, t)
+		}
+	}
+	for _, t := range e.RDates {
+		if t.Before(at) {
+			head.RDates = append(head.RDates, t)
+		} else if !t.Equal(at) {
+			tail.RDates = append(tail.RDates, t)
+		}
+	}
+
+	// Пустые (не nil) списки, чтобы репозиторий сохранил их, а не оставил прежние значения.
+	if head.ExDates == nil {
+		head.ExDates = []time.Time{}
+	}
+	if head.RDates == nil {
+		head.RDates = []time.Time{}
+	}
+	return head, tail, nil
+}
+
+// applyPatch переносит в dst поля fields из patch, в том числе пустые, — как PGEventStorage.UpdateEvent.
+func applyPatch(dst *repos.Event, patch *repos.Event, fields repos.EventFields) {
+	if fields.Has(repos.FieldTitle) {
+		dst.Title = patch.Title
+	}
+	if fields.Has(repos.FieldDescription) {
+		dst.Description = patch.Description
+	}
+	if fields.Has(repos.FieldStartTime) {
+		dst.StartTime = patch.StartTime
+	}
+	if fields.Has(repos.FieldEndTime) {
+		dst.EndTime = patch.EndTime
+	}
+	if fields.Has(repos.FieldOwnerID) {
+		dst.OwnerID = patch.OwnerID
+	}
+	if fields.Has(repos.FieldCalendarID) {
+		dst.CalendarID = patch.CalendarID
+	}
+	if fields.Has(repos.FieldRRule) {
+		dst.RRule = patch.RRule
+	}
+	if fields.Has(repos.FieldExDates) {
+		dst.ExDates = patch.ExDates
+	}
+	if fields.Has(repos.FieldRDates) {
+		dst.RDates = patch.RDates
+	}
+	if fields.Has(repos.FieldReminders) {
+		dst.Reminders = patch.Reminders
+	}
+	if fields.Has(repos.FieldTimeZone) {
+		dst.TimeZone = patch.TimeZone
+	}
+}
+
+// OccursBetween сообщает, пересекается ли с окном [from, to) хотя бы одно вхождение
+// событий events — разового события или серии вместе с её переопределениями.
+// В отличие от ExpandEvents не разворачивает серию целиком, поэтому окно может быть
+// сколь угодно длинным.
+func OccursBetween(events []repos.Event, from, to time.Time) (bool, error) {
+	overridden := make(map[occurrenceKey]struct{})
+	for _, e := range events {
+		if e.RecurringEventID == "" {
+			continue
+		}
+		if overlaps(e.StartTime, e.EndTime, from, to) {
+			return true, nil
+		}
+		overridden[occurrenceKey{e.RecurringEventID, e.RecurrenceID.UnixNano()}] = struct{}{}
+	}
+
+	for _, e := range events {
+		if e.RecurringEventID != "" {
+			continue
+		}
+		if e.RRule == "" {
+			if overlaps(e.StartTime, e.EndTime, from, to) {
+				return true, nil
+			}
+			continue
+		}
+
+		set, err := recurrenceSet(e)
+		if err != nil {
+			return false, err
+		}
+		duration := e.EndTime.Sub(e.StartTime)
+		for start := set.After(from.Add(-duration), true); !start.IsZero() && start.Before(to); start = set.After(start, false) {
+			if _, ok := overridden[occurrenceKey{e.ID, start.UnixNano()}]; ok {
+				continue
+			}
+			if overlaps(start, start.Add(duration), from, to) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
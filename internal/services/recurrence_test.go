@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"calendar/internal/repos"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// series возвращает серию длительностью duration с началом start и правилом rule.
+func series(start time.Time, duration time.Duration, rule string) repos.Event {
+	return repos.Event{
+		ID:        "series",
+		StartTime: start,
+		EndTime:   start.Add(duration),
+		RRule:     rule,
+		TimeZone:  start.Location().String(),
+	}
+}
+
+// occurrenceStarts разворачивает e в окне [from, to) и возвращает начала вхождений.
+func occurrenceStarts(t *testing.T, e repos.Event, from, to time.Time) []time.Time {
+	t.Helper()
+	occurrences, err := expandEvent(e, from, to)
+	if err != nil {
+		t.Fatalf("expandEvent: %v", err)
+	}
+	starts := make([]time.Time, 0, len(occurrences))
+	for _, o := range occurrences {
+		starts = append(starts, o.StartTime)
+	}
+	return starts
+}
+
+func equalTimes(a, b []time.Time) bool {
+	return slices.EqualFunc(a, b, time.Time.Equal)
+}
+
+func TestExpandEvent(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	day := func(d, h, m int) time.Time { return time.Date(2026, time.March, d, h, m, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		event    repos.Event
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			name: "weekly series keeps local time across DST",
+			// 29 марта 2026 Берлин переходит на летнее время; BYDAY и 00:30 считаются по-местному.
+			event: series(time.Date(2026, time.March, 23, 0, 30, 0, 0, berlin), time.Hour, "FREQ=WEEKLY"),
+			from:  day(20, 0, 0),
+			to:    day(31, 0, 0).AddDate(0, 0, 10),
+			want: []time.Time{
+				time.Date(2026, time.March, 23, 0, 30, 0, 0, berlin),
+				time.Date(2026, time.March, 30, 0, 30, 0, 0, berlin),
+				time.Date(2026, time.April, 6, 0, 30, 0, 0, berlin),
+			},
+		},
+		{
+			name: "exdates and rdates",
+			event: func() repos.Event {
+				e := series(day(1, 9, 0), time.Hour, "FREQ=DAILY;COUNT=3")
+				e.ExDates = []time.Time{day(2, 9, 0)}
+				e.RDates = []time.Time{day(10, 15, 0)}
+				return e
+			}(),
+			from: day(1, 0, 0),
+			to:   day(31, 0, 0),
+			want: []time.Time{day(1, 9, 0), day(3, 9, 0), day(10, 15, 0)},
+		},
+		{
+			name:  "occurrence started before the window overlaps it",
+			event: series(day(1, 9, 0), 2*time.Hour, "FREQ=DAILY"),
+			from:  day(2, 10, 0),
+			to:    day(3, 10, 0),
+			want:  []time.Time{day(2, 9, 0), day(3, 9, 0)},
+		},
+		{
+			name:  "window end is exclusive",
+			event: series(day(1, 9, 0), time.Hour, "FREQ=DAILY"),
+			from:  day(1, 0, 0),
+			to:    day(3, 9, 0),
+			want:  []time.Time{day(1, 9, 0), day(2, 9, 0)},
+		},
+		{
+			name:  "zero-length occurrence at the window start",
+			event: series(day(1, 9, 0), 0, "FREQ=DAILY"),
+			from:  day(2, 9, 0),
+			to:    day(3, 0, 0),
+			want:  []time.Time{day(2, 9, 0)},
+		},
+		{
+			name:  "series ended before the window",
+			event: series(day(1, 9, 0), time.Hour, "FREQ=DAILY;UNTIL=20260305T000000Z"),
+			from:  day(10, 0, 0),
+			to:    day(20, 0, 0),
+			want:  []time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrences, err := expandEvent(tt.event, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("expandEvent: %v", err)
+			}
+
+			got := make([]time.Time, 0, len(occurrences))
+			for _, o := range occurrences {
+				got = append(got, o.StartTime)
+				if !o.RecurrenceID.Equal(o.StartTime) || o.RecurringEventID != tt.event.ID {
+					t.Errorf("occurrence %v: recurrence id %v of %q", o.StartTime, o.RecurrenceID, o.RecurringEventID)
+				}
+				if o.RRule != "" || o.ExDates != nil || o.RDates != nil {
+					t.Errorf("occurrence %v keeps recurrence rules", o.StartTime)
+				}
+				if d := o.EndTime.Sub(o.StartTime); d != tt.event.EndTime.Sub(tt.event.StartTime) {
+					t.Errorf("occurrence %v lasts %v", o.StartTime, d)
+				}
+			}
+			if !equalTimes(got, tt.want) {
+				t.Errorf("starts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsOccurrence(t *testing.T) {
+	start := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+	e := series(start, time.Hour, "FREQ=WEEKLY;COUNT=4")
+	e.ExDates = []time.Time{start.AddDate(0, 0, 7)}
+	e.RDates = []time.Time{start.AddDate(0, 0, 3)}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"first occurrence", start, true},
+		{"later occurrence", start.AddDate(0, 0, 14), true},
+		{"same day at another time", start.Add(time.Hour), false},
+		{"excluded", start.AddDate(0, 0, 7), false},
+		{"extra date", start.AddDate(0, 0, 3), true},
+		{"after count is exhausted", start.AddDate(0, 0, 28), false},
+		{"before the series", start.AddDate(0, 0, -7), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isOccurrence(e, tt.at)
+			if err != nil {
+				t.Fatalf("isOccurrence: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("isOccurrence(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown time zone", func(t *testing.T) {
+		bad := e
+		bad.TimeZone = "Mars/Olympus"
+		if _, err := isOccurrence(bad, start); !errors.Is(err, ErrInvalidTimeZone) {
+			t.Errorf("err = %v, want ErrInvalidTimeZone", err)
+		}
+	})
+}
+
+func TestSplitSeries(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	local := func(d, h int) time.Time { return time.Date(2026, time.March, d, h, 0, 0, 0, berlin) }
+	from, to := local(1, 0), local(31, 0)
+
+	tests := []struct {
+		name     string
+		event    repos.Event
+		at       time.Time
+		wantHead []time.Time
+		wantTail []time.Time
+		wantErr  error
+	}{
+		{
+			name:     "unbounded series in a local zone",
+			event:    series(local(25, 9), time.Hour, "FREQ=DAILY"),
+			at:       local(28, 9),
+			wantHead: []time.Time{local(25, 9), local(26, 9), local(27, 9)},
+			// Продолжение пересекает переход на летнее время 29 марта и остаётся в 09:00.
+			wantTail: []time.Time{local(28, 9), local(29, 9), local(30, 9)},
+		},
+		{
+			name:     "count is shared between head and tail",
+			event:    series(local(1, 9), time.Hour, "FREQ=DAILY;COUNT=5"),
+			at:       local(3, 9),
+			wantHead: []time.Time{local(1, 9), local(2, 9)},
+			wantTail: []time.Time{local(3, 9), local(4, 9), local(5, 9)},
+		},
+		{
+			name:     "until is kept by the tail",
+			event:    series(local(1, 9), time.Hour, "FREQ=DAILY;UNTIL=20260304T235959Z"),
+			at:       local(3, 9),
+			wantHead: []time.Time{local(1, 9), local(2, 9)},
+			wantTail: []time.Time{local(3, 9), local(4, 9)},
+		},
+		{
+			name: "exdates and rdates go to their half",
+			event: func() repos.Event {
+				e := series(local(1, 9), time.Hour, "FREQ=DAILY;COUNT=6")
+				e.ExDates = []time.Time{local(2, 9), local(5, 9)}
+				e.RDates = []time.Time{local(1, 18), local(4, 18)}
+				return e
+			}(),
+			at:       local(4, 9),
+			wantHead: []time.Time{local(1, 9), local(1, 18), local(3, 9)},
+			wantTail: []time.Time{local(4, 9), local(4, 18), local(6, 9)},
+		},
+		{
+			name:    "count is exhausted before the split",
+			event:   series(local(1, 9), time.Hour, "FREQ=DAILY;COUNT=2"),
+			at:      local(5, 9),
+			wantErr: ErrInvalidRecurrence,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, tail, err := splitSeries(tt.event, tt.at)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitSeries: %v", err)
+			}
+
+			if tail.ID != "" || !tail.StartTime.Equal(tt.at) {
+				t.Errorf("tail starts at %v with id %q", tail.StartTime, tail.ID)
+			}
+			if got := occurrenceStarts(t, head, from, to); !equalTimes(got, tt.wantHead) {
+				t.Errorf("head = %v, want %v", got, tt.wantHead)
+			}
+			if got := occurrenceStarts(t, tail, from, to); !equalTimes(got, tt.wantTail) {
+				t.Errorf("tail = %v, want %v", got, tt.wantTail)
+			}
+		})
+	}
+}
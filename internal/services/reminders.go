@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"calendar/internal/repos"
+)
+
+// MaxReminderLead — максимальное время, за которое можно напомнить о событии.
+const MaxReminderLead = 28 * 24 * time.Hour
+
+// ErrInvalidReminders — напоминания события заданы некорректно.
+var ErrInvalidReminders = errors.New("invalid reminders")
+
+// validateReminders проверяет, что каждое напоминание лежит в [0, MaxReminderLead] и не повторяется.
+func validateReminders(e repos.Event) error {
+	seen := make(map[int]struct{}, len(e.Reminders))
+	for _, m := range e.Reminders {
+		if m < 0 || time.Duration(m)*time.Minute > MaxReminderLead {
+			return fmt.Errorf("%w: %d minutes is out of range", ErrInvalidReminders, m)
+		}
+		if _, ok := seen[m]; ok {
+			return fmt.Errorf("%w: duplicate reminder %d minutes", ErrInvalidReminders, m)
+		}
+		seen[m] = struct{}{}
+	}
+	return nil
+}
+
+// NextReminderAt возвращает, когда после now сработает ближайшее напоминание серии e,
+// или nil, если вхождений с напоминаниями больше не будет. Переопределения вхождений
+// не учитываются: у них собственные напоминания, а перенесённое вхождение серии даёт лишь
+// более раннюю (то есть безопасную) оценку.
+func NextReminderAt(e repos.Event, now time.Time) (*time.Time, error) {
+	if e.RRule == "" || len(e.Reminders) == 0 {
+		return nil, nil
+	}
+	set, err := recurrenceSet(e)
+	if err != nil {
+		return nil, err
+	}
+	lead := time.Duration(slices.Max(e.Reminders)) * time.Minute
+
+	var next *time.Time
+	earliest := func(at time.Time) {
+		if at.After(now) && (next == nil || at.Before(*next)) {
+			next = &at
+		}
+	}
+	// Часть напоминаний вхождений, начинающихся до now+lead, уже могла сработать.
+	for _, start := range set.Between(now, now.Add(lead), true) {
+		for _, m := range e.Reminders {
+			earliest(start.Add(-time.Duration(m) * time.Minute))
+		}
+	}
+	// У последующих вхождений все напоминания впереди, и раньше всех — самое раннее
+	// напоминание первого из них.
+	if start := set.After(now.Add(lead), false); !start.IsZero() {
+		earliest(start.Add(-lead))
+	}
+	return next, nil
+}
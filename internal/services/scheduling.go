@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Ограничения и значения по умолчанию для подбора времени встречи.
+const (
+	MaxSuggestWindow    = 62 * 24 * time.Hour
+	MaxMeetingDuration  = 24 * time.Hour
+	DefaultSuggestStep  = 15 * time.Minute
+	MinSuggestStep      = 5 * time.Minute
+	DefaultSuggestLimit = 10
+	MaxSuggestLimit     = 100
+)
+
+// optionalWeight — вклад необязательного участника в оценку слота относительно обязательного.
+const optionalWeight = 0.5
+
+// ErrInvalidSuggestQuery — запрос подбора времени задан некорректно.
+var ErrInvalidSuggestQuery = errors.New("invalid scheduling query")
+
+// WorkingHours — рабочее время пользователя: с Start до End минут от полуночи
+// по его часовому поясу в дни Days. Смены через полночь не поддерживаются.
+// Location nil — часовой пояс календаря пользователя по умолчанию.
+type WorkingHours struct {
+	Location *time.Location
+	Start    int
+	End      int
+	Days     []time.Weekday
+}
+
+// DefaultWorkingHours — рабочее время тех, для кого оно не задано: пн–пт с 9 до 18
+// в поясе календаря по умолчанию.
+var DefaultWorkingHours = WorkingHours{
+	Start: 9 * 60,
+	End:   18 * 60,
+	Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+}
+
+// contains сообщает, лежит ли промежуток [start, end) целиком в одном рабочем дне.
+func (wh WorkingHours) contains(start, end time.Time) bool {
+	local := start.In(wh.Location)
+	workday := false
+	for _, d := range wh.Days {
+		if local.Weekday() == d {
+			workday = true
+			break
+		}
+	}
+	if !workday {
+		return false
+	}
+
+	y, m, d := local.Date()
+	dayStart := time.Date(y, m, d, 0, wh.Start, 0, 0, wh.Location)
+	dayEnd := time.Date(y, m, d, 0, wh.End, 0, 0, wh.Location)
+	return !start.Before(dayStart) && !end.After(dayEnd)
+}
+
+// SuggestAttendee — участник встречи, для которой подбирается время.
+type SuggestAttendee struct {
+	UserID string
+	// Optional — участник необязательный: его занятость не исключает слот, а только снижает оценку.
+	Optional     bool
+	WorkingHours WorkingHours
+}
+
+// SuggestQuery — параметры подбора времени встречи.
+type SuggestQuery struct {
+	Attendees []SuggestAttendee
+	Duration  time.Duration
+	// From и To — окно [From, To), в котором должна целиком пройти встреча.
+	From time.Time
+	To   time.Time
+	// Step — шаг, с которым перебираются начала слотов (кратные Step от начала эпохи);
+	// 0 — DefaultSuggestStep.
+	Step time.Duration
+	// Limit — сколько слотов вернуть; 0 — DefaultSuggestLimit.
+	Limit int
+}
+
+// Suggestion — предложенный слот.
+type Suggestion struct {
+	Interval
+	// Score — доля (с учётом веса необязательных) участников, которые свободны и находятся
+	// в рабочем времени: 1 — всем удобно.
+	Score float64
+	// Busy — необязательные участники, занятые в это время.
+	Busy []string
+	// OutsideWorkingHours — участники, для которых слот выходит за рабочее время.
+	OutsideWorkingHours []string
+}
+
+// SuggestTimes подбирает время встречи: перебирает слоты длительностью q.Duration в окне,
+// отбрасывает те, где занят хотя бы один обязательный участник, и возвращает лучшие
+// по Score (при равной оценке — более ранние). Занятость считается как в FreeBusy.
+func (s *EventsServiceImpl) SuggestTimes(ctx context.Context, q SuggestQuery) ([]Suggestion, error) {
+	if q.Step == 0 {
+		q.Step = DefaultSuggestStep
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultSuggestLimit
+	}
+	if err := validateSuggestQuery(q); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(q.Attendees))
+	for _, a := range q.Attendees {
+		userIDs = append(userIDs, a.UserID)
+	}
+	busy, err := s.FreeBusy(ctx, userIDs, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	// Копия, чтобы не менять участников вызывающего.
+	q.Attendees = slices.Clone(q.Attendees)
+	for i, a := range q.Attendees {
+		if a.WorkingHours.Location != nil {
+			continue
+		}
+		if q.Attendees[i].WorkingHours.Location, err = s.defaultLocation(ctx, a.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	var total float64
+	for _, a := range q.Attendees {
+		total += weight(a)
+	}
+
+	var suggestions []Suggestion
+	for start := ceilTime(q.From, q.Step); !start.Add(q.Duration).After(q.To); start = start.Add(q.Step) {
+		end := start.Add(q.Duration)
+		sg, ok := rateSlot(q.Attendees, busy, start, end)
+		if !ok {
+			continue
+		}
+		sg.Score = math.Round(sg.Score/total*100) / 100
+		suggestions = append(suggestions, sg)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > q.Limit {
+		suggestions = suggestions[:q.Limit]
+	}
+	return suggestions, nil
+}
+
+// defaultLocation возвращает часовой пояс календаря userID по умолчанию или UTC,
+// если календарей у пользователя ещё нет.
+func (s *EventsServiceImpl) defaultLocation(ctx context.Context, userID string) (*time.Location, error) {
+	calendars, err := s.repo.ListCalendars(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(calendars) == 0 || !calendars[0].IsDefault {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(calendars[0].TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	return loc, nil
+}
+
+// rateSlot оценивает слот [start, end); ok=false, если занят обязательный участник.
+// Score возвращается в абсолютных единицах (сумма весов удобных участников).
+func rateSlot(attendees []SuggestAttendee, busy map[string][]Interval, start, end time.Time) (Suggestion, bool) {
+	sg := Suggestion{
+		Interval:            Interval{Start: start, End: end},
+		Busy:                []string{},
+		OutsideWorkingHours: []string{},
+	}
+	for _, a := range attendees {
+		if isBusy(busy[a.UserID], start, end) {
+			if !a.Optional {
+				return Suggestion{}, false
+			}
+			sg.Busy = append(sg.Busy, a.UserID)
+			continue
+		}
+		if !a.WorkingHours.contains(start, end) {
+			sg.OutsideWorkingHours = append(sg.OutsideWorkingHours, a.UserID)
+			continue
+		}
+		sg.Score += weight(a)
+	}
+	return sg, true
+}
+
+// isBusy сообщает, пересекается ли [start, end) с одним из упорядоченных непересекающихся промежутков.
+func isBusy(intervals []Interval, start, end time.Time) bool {
+	i := sort.Search(len(intervals), func(i int) bool {
+		return intervals[i].End.After(start)
+	})
+	return i < len(intervals) && intervals[i].Start.Before(end)
+}
+
+func weight(a SuggestAttendee) float64 {
+	if a.Optional {
+		return optionalWeight
+	}
+	return 1
+}
+
+// ceilTime округляет t вверх до кратного step от начала эпохи.
+func ceilTime(t time.Time, step time.Duration) time.Time {
+	r := t.Truncate(step)
+	if r.Before(t) {
+		r = r.Add(step)
+	}
+	return r
+}
+
+func validateSuggestQuery(q SuggestQuery) error {
+	if len(q.Attendees) == 0 || len(q.Attendees) > MaxFreeBusyUsers {
+		return fmt.Errorf("%w: between 1 and %d attendees are required", ErrInvalidSuggestQuery, MaxFreeBusyUsers)
+	}
+	seen := make(map[string]struct{}, len(q.Attendees))
+	for _, a := range q.Attendees {
+		if a.UserID == "" {
+			return fmt.Errorf("%w: empty user id", ErrInvalidSuggestQuery)
+		}
+		if _, ok := seen[a.UserID]; ok {
+			return fmt.Errorf("%w: duplicate attendee %q", ErrInvalidSuggestQuery, a.UserID)
+		}
+		seen[a.UserID] = struct{}{}
+
+		wh := a.WorkingHours
+		if wh.Start < 0 || wh.End > 24*60 || wh.Start >= wh.End || len(wh.Days) == 0 {
+			return fmt.Errorf("%w: invalid working hours of %q", ErrInvalidSuggestQuery, a.UserID)
+		}
+	}
+	if q.Duration <= 0 || q.Duration > MaxMeetingDuration {
+		return fmt.Errorf("%w: duration must be positive and at most %d hours", ErrInvalidSuggestQuery, MaxMeetingDuration/time.Hour)
+	}
+	if q.Step < MinSuggestStep {
+		return fmt.Errorf("%w: step must be at least %d minutes", ErrInvalidSuggestQuery, MinSuggestStep/time.Minute)
+	}
+	if q.Limit < 1 || q.Limit > MaxSuggestLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSuggestQuery, MaxSuggestLimit)
+	}
+	if !q.From.Before(q.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidSuggestQuery)
+	}
+	if q.To.Sub(q.From) > MaxSuggestWindow {
+		return fmt.Errorf("%w: window is longer than %d days", ErrInvalidSuggestQuery, MaxSuggestWindow/(24*time.Hour))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Package tracing настраивает трассировку OpenTelemetry: экспортёр спанов, сэмплирование
+// и передачу контекста трассы между сервисами (W3C Trace Context).
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+
+	"calendar/internal/config"
+)
+
+// Provider — глобальный источник спанов сервиса.
+type Provider struct {
+	tp *sdktrace.TracerProvider
+	// out — файл экспортёра "file", закрывается при Shutdown.
+	out io.Closer
+}
+
+// NewProvider создаёт экспортёр из cfg.Tracing и делает Provider глобальным для otel.
+// Передача контекста трассы (traceparent, baggage) включается и при exporter "none",
+// чтобы сервис не обрывал трассы, проходящие через него.
+func NewProvider(cfg *config.Config) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, out, err := newExporter(cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return &Provider{}, nil
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.Tracing.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return &Provider{tp: tp, out: out}, nil
+}
+
+// newExporter создаёт экспортёр по названию; для "none" (и пустого) — nil.
+func newExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.Exporter {
+	case "", "none":
+		return nil, nil, nil
+
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+		return exporter, nil, nil
+
+	case "stdout":
+		exporter, err := stdouttrace.New()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil, nil
+
+	case "file":
+		if cfg.File == "" {
+			return nil, nil, fmt.Errorf("tracing.file is required for the file exporter")
+		}
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open traces file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		return exporter, f, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+}
+
+// Shutdown отправляет накопленные спаны и останавливает экспортёр.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.tp == nil {
+		return nil
+	}
+	err := p.tp.Shutdown(ctx)
+	if p.out != nil {
+		if closeErr := p.out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Middleware начинает серверный спан на каждый запрос (или продолжает трассу из traceparent).
+// Спан называется по методу и маршруту mux, под который попал запрос (например,
+// "GET /api/events/"), а не по пути, чтобы ID не попадали в имена спанов.
+func Middleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			route := "unmatched"
+			if _, pattern := mux.Handler(r); pattern != "" {
+				route = pattern
+			}
+			return r.Method + " " + route
+		}),
+	)
+}
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_events_recurrence;
+
+DELETE FROM events WHERE recurring_event_id IS NOT NULL;
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS recurrence_id,
+    DROP COLUMN IF EXISTS recurring_event_id,
+    DROP COLUMN IF EXISTS rdates,
+    DROP COLUMN IF EXISTS exdates,
+    DROP COLUMN IF EXISTS rrule;
@@ -0,0 +1,10 @@
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS rrule              TEXT          NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS exdates            TIMESTAMPTZ[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS rdates             TIMESTAMPTZ[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS recurring_event_id UUID REFERENCES events (id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS recurrence_id      TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_recurrence
+    ON events (recurring_event_id, recurrence_id)
+    WHERE recurring_event_id IS NOT NULL;
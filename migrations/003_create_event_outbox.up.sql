@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id         BIGSERIAL PRIMARY KEY,
+    event_id   UUID        NOT NULL,
+    payload    JSONB       NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_unsent
+    ON event_outbox (id)
+    WHERE sent_at IS NULL;
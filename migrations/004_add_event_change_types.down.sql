@@ -0,0 +1,12 @@
+DELETE FROM event_outbox WHERE after IS NULL;
+
+ALTER TABLE event_outbox
+    DROP COLUMN IF EXISTS before,
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS type,
+    ALTER COLUMN after SET NOT NULL;
+
+ALTER TABLE event_outbox RENAME COLUMN after TO payload;
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS version;
@@ -0,0 +1,14 @@
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE event_outbox RENAME COLUMN payload TO after;
+
+ALTER TABLE event_outbox
+    ALTER COLUMN after DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS type    TEXT   NOT NULL DEFAULT 'updated',
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS before  JSONB;
+
+ALTER TABLE event_outbox
+    ALTER COLUMN type DROP DEFAULT,
+    ALTER COLUMN version DROP DEFAULT;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS reminder_deliveries;
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS reminders;
@@ -0,0 +1,17 @@
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS reminders INTEGER[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS reminder_deliveries (
+    id               BIGSERIAL PRIMARY KEY,
+    event_id         UUID        NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    occurrence_start TIMESTAMPTZ NOT NULL,
+    minutes_before   INTEGER     NOT NULL,
+    fire_at          TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at          TIMESTAMPTZ,
+    UNIQUE (event_id, occurrence_start, minutes_before)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminder_deliveries_unsent
+    ON reminder_deliveries (id)
+    WHERE sent_at IS NULL;
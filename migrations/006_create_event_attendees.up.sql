@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS event_attendees (
+    event_id   UUID        NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id    TEXT        NOT NULL,
+    status     TEXT        NOT NULL DEFAULT 'needs-action'
+        CHECK (status IN ('needs-action', 'accepted', 'declined', 'tentative')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (event_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_attendees_user
+    ON event_attendees (user_id, event_id);
@@ -0,0 +1,8 @@
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS ical_uid TEXT;
+
+-- UID из iCalendar однозначно определяет событие (серию) внутри календаря владельца;
+-- переопределения вхождений наследуют UID серии.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_ical_uid
+    ON events (owner_id, ical_uid)
+    WHERE ical_uid IS NOT NULL AND recurring_event_id IS NULL;
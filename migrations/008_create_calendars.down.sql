@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_events_calendar_start_time;
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS calendar_id;
+
+DROP TABLE IF EXISTS calendars;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS calendars (
+    id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id   TEXT        NOT NULL,
+    name       TEXT        NOT NULL,
+    color      TEXT        NOT NULL DEFAULT '',
+    timezone   TEXT        NOT NULL DEFAULT 'UTC',
+    is_default BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendars_owner
+    ON calendars (owner_id);
+
+-- У каждого владельца не больше одного календаря по умолчанию.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendars_default
+    ON calendars (owner_id)
+    WHERE is_default;
+
+-- Существующие события переезжают в календарь по умолчанию своего владельца.
+INSERT INTO calendars (owner_id, name, is_default)
+SELECT DISTINCT owner_id, 'Default', TRUE
+FROM events
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS calendar_id UUID REFERENCES calendars (id) ON DELETE CASCADE;
+
+UPDATE events e
+SET calendar_id = c.id
+FROM calendars c
+WHERE c.owner_id = e.owner_id AND c.is_default AND e.calendar_id IS NULL;
+
+ALTER TABLE events
+    ALTER COLUMN calendar_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_calendar_start_time
+    ON events (calendar_id, start_time);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS calendar_shares (
+    id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id    TEXT        NOT NULL,
+    -- NULL — доступ ко всем календарям владельца, в том числе созданным позже.
+    calendar_id UUID REFERENCES calendars (id) ON DELETE CASCADE,
+    grantee_id  TEXT        NOT NULL,
+    role        TEXT        NOT NULL
+        CHECK (role IN ('free-busy', 'reader', 'writer', 'owner')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_shares_calendar
+    ON calendar_shares (calendar_id, grantee_id)
+    WHERE calendar_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_shares_owner
+    ON calendar_shares (owner_id, grantee_id)
+    WHERE calendar_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_calendar_shares_grantee
+    ON calendar_shares (grantee_id, owner_id);
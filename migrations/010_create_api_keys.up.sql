@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    -- Пользователь (или команда), от имени которого действует ключ.
+    owner_id   TEXT        NOT NULL,
+    name       TEXT        NOT NULL,
+    -- Начало ключа, чтобы отличать ключи в списке; сам ключ не хранится.
+    prefix     TEXT        NOT NULL,
+    key_hash   TEXT        NOT NULL UNIQUE,
+    scopes     TEXT[]      NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_owner
+    ON api_keys (owner_id, created_at);
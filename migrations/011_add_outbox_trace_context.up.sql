@@ -0,0 +1,4 @@
+-- Контекст трассировки (W3C traceparent/tracestate) запроса, изменившего событие,
+-- чтобы отправка в Kafka продолжала его трассу.
+ALTER TABLE event_outbox
+    ADD COLUMN IF NOT EXISTS trace_context JSONB;
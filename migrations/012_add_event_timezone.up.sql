@@ -0,0 +1,4 @@
+-- Часовой пояс IANA, в котором разворачивается серия: BYDAY, время вхождений и переходы
+-- на летнее время считаются от DTSTART в этом поясе, а не в UTC.
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_events_reminders_next_at;
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS reminders_next_at;
@@ -0,0 +1,9 @@
+-- Когда серию с напоминаниями снова нужно развернуть: не раньше ближайшего её напоминания.
+-- NULL — пересчитать на ближайшем такте планировщика (новая или изменённая серия),
+-- 'infinity' — напоминаний у серии больше не будет.
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS reminders_next_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_events_reminders_next_at
+    ON events (reminders_next_at)
+    WHERE rrule <> '' AND cardinality(reminders) > 0;
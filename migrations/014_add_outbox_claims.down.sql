@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_event_outbox_unsent_by_event;
+
+ALTER TABLE event_outbox
+    DROP COLUMN IF EXISTS claimed_until;
@@ -0,0 +1,8 @@
+-- До какого момента запись outbox закреплена за экземпляром, который отправляет её в Kafka.
+-- NULL или прошедший момент — запись свободна.
+ALTER TABLE event_outbox
+    ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_unsent_by_event
+    ON event_outbox (event_id, id)
+    WHERE sent_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE reminder_deliveries
+    DROP COLUMN IF EXISTS claimed_until;
@@ -0,0 +1,4 @@
+-- До какого момента напоминание закреплено за экземпляром, который отправляет его в Kafka.
+-- NULL или прошедший момент — напоминание свободно.
+ALTER TABLE reminder_deliveries
+    ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
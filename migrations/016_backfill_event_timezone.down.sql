@@ -0,0 +1 @@
+-- Прежний пояс событий не сохранился: откат оставляет пояс календаря.
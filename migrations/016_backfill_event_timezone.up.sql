@@ -0,0 +1,7 @@
+-- События с поясом по умолчанию из 012 ('UTC') разворачиваются в поясе своего календаря,
+-- как и новые события без явного пояса.
+UPDATE events e
+SET timezone = c.timezone
+FROM calendars c
+WHERE c.id = e.calendar_id
+  AND e.timezone = 'UTC';